@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"os"
 	gotime "time"
 
+	"github.com/prasadchandan/go_lbm/lbm"
 	"github.com/prasadchandan/go_lbm/uiengine"
 
 	"golang.org/x/mobile/event/size"
@@ -13,13 +16,16 @@ import (
 
 // AppProperties holds the state of the application
 type AppProperties struct {
-	Solver       *Solver
+	Solver       *lbm.Solver
 	UI           *uiengine.UiEngine
 	Device       *uiengine.DeviceSpecs
 	Menu         *uiengine.Window
 	BottomBar    *uiengine.Window
 	DebugWindow  *uiengine.Window
 	Fps          *uiengine.Label
+	Reynolds     *uiengine.Label
+	Forces       *uiengine.Label
+	Strouhal     *uiengine.Label
 	TouchHandler *uiengine.UiGesture
 
 	// Grid Properties
@@ -32,13 +38,42 @@ type AppProperties struct {
 	// Flow Properties
 	Fvel           float32
 	Fvis           float32
+	Smagorinsky    float32 // Smagorinsky constant, 0 disables LES
+	Spin           float32 // Surface speed of the barrier over Fvel
+	Force          int     // Index into externalForces
+	Stokes         float32 // Stokes number of the tracers
+	Rayleigh       int     // Index into rayleighNumbers
+	Prandtl        int     // Index into prandtlNumbers
+	Interaction    int     // Index into interactionStrengths
+	Wetting        int     // Index into contactAngles
+	ViscosityRatio int     // Index into viscosityRatios
+	Tension        int     // Index into surfaceTensions
+	Porosity       int     // Index into solidFractions
 	PxPerSimSquare int
 
 	// Disp properties
 	Plot      int // Flow property plotted
+	Tracers   int // Tracer seeding mode
+	Dye       int // Where dye enters the flow
 	Barrier   int // Type of barrier
+	Collision int // Index into lbm.CollisionOperatorNames
+	Magic     int // Index into magicParameters
+	Ghost     int // Index into ghostRates
 	RenderOpt int // switch between gl.NEAREST, LINEAR and MIPMAP
 
+	// Streamline overlay
+	Streamlines     int // Index into streamlineSpacings
+	StreamlineColor int // Index into streamlineColors
+
+	// Touch interaction with the simulation
+	TouchMode int  // What dragging and tapping the simulation does
+	DragProbe int  // Probe being dragged, -1 if none
+	ProbeTap  bool // Touch started on the simulation in probe mode
+
+	// Boundary conditions of the domain edges
+	Boundaries [lbm.NumEdges]lbm.BoundaryType
+	Edge       int // Edge being edited in the menu
+
 	// Feedback menu
 	ShowMenu bool
 
@@ -47,11 +82,24 @@ type AppProperties struct {
 	ProcessingClick       bool
 	ProcessingClickBottom bool
 
+	// Previous touch position, used to drag the fluid
+	OldTouchX float32
+	OldTouchY float32
+
 	PauseSimulation bool
 	ResetSimulation bool
 	GridInitalized  bool
 }
 
+// Touch modes
+const (
+	TouchDragFluid = iota // dragging pushes the fluid
+	TouchProbe            // tapping places or removes probes, dragging moves them
+	TouchSource           // dragging paints dye sources
+	TouchPorous           // dragging paints porous sites
+	NumTouchModes
+)
+
 // TouchToGrid converts from pixel coordinates to grid coordinates
 func (a *AppProperties) TouchToGrid() (i, j int) {
 	i = int(a.TouchHandler.TouchX / float32(a.PxPerSimSquare))
@@ -62,6 +110,9 @@ func (a *AppProperties) TouchToGrid() (i, j int) {
 // InitTouchHandler initializes the touch handler with initial state
 func (a *AppProperties) InitTouchHandler() {
 	a.TouchHandler = uiengine.CreateUiGesture()
+	a.OldTouchX = -1
+	a.OldTouchY = -1
+	a.DragProbe = -1
 }
 
 // InitDeviceSpecs sets up default display properties
@@ -89,7 +140,191 @@ func (a *AppProperties) InitApp(glctx gl.Context) {
 
 // ResetSolver resets the solver to the initial starting state
 func (a *AppProperties) ResetSolver() {
+	a.ApplyPhysics()
 	solver.InitalizeLattice(a.XGrid, a.YGrid, a.Fvel, a.Fvis, a.Barrier)
+	solver.SetCollisionOperator(a.CollisionOperator())
+}
+
+// ApplyPhysics passes the boundary conditions and the physical models
+// selected in the menu to the solver, before the lattice is initialized
+func (a *AppProperties) ApplyPhysics() {
+	a.ApplyBoundaries()
+	a.ApplyThermal()
+	a.ApplyMultiphase()
+	a.ApplyImmiscible()
+}
+
+// ApplyThermal solves the temperature for the natural convection barriers
+// and turns it off for the others
+func (a *AppProperties) ApplyThermal() {
+	if a.Barrier == lbm.BENARD || a.Barrier == lbm.HEATED {
+		solver.EnableThermal(rayleighNumbers[a.Rayleigh], prandtlNumbers[a.Prandtl])
+	} else {
+		solver.DisableThermal()
+	}
+}
+
+// ApplyMultiphase turns the Shan-Chen model on for the multiphase barriers
+// and off for the others
+func (a *AppProperties) ApplyMultiphase() {
+	if multiphase(a.Barrier) {
+		solver.EnableShanChen(interactionStrengths[a.Interaction])
+		solver.SetContactAngle(contactAngles[a.Wetting])
+	} else {
+		solver.DisableShanChen()
+	}
+}
+
+// ApplyImmiscible splits the fluid in two for the fingering barrier and
+// merges it back for the others
+func (a *AppProperties) ApplyImmiscible() {
+	if a.Barrier == lbm.FINGERS {
+		solver.EnableImmiscible(a.Fvis*viscosityRatios[a.ViscosityRatio], surfaceTensions[a.Tension])
+	} else {
+		solver.DisableImmiscible()
+	}
+}
+
+// ApplyBoundaries passes the boundary conditions selected in the menu to the solver
+func (a *AppProperties) ApplyBoundaries() {
+	for edge, btype := range a.Boundaries {
+		solver.SetBoundary(edge, btype)
+	}
+}
+
+// CollisionOperator creates the collision operator selected in the menu
+func (a *AppProperties) CollisionOperator() lbm.CollisionOperator {
+	op, err := lbm.NewCollisionOperator(lbm.CollisionOperatorNames()[a.Collision])
+	if err != nil {
+		fmt.Println(err)
+		return lbm.BGK{}
+	}
+	if trt, ok := op.(*lbm.TRT); ok {
+		trt.Magic = magicParameters[a.Magic]
+	}
+	if mrt, ok := op.(*lbm.MRT); ok {
+		mrt.SGhost = ghostRates[a.Ghost]
+	}
+	return op
+}
+
+// DragFluidCheck converts a touch drag into a push on the fluid, returns
+// nil if the user is not interactively dragging the fluid
+func (a *AppProperties) DragFluidCheck(s *lbm.Solver) *lbm.DragFluidProperties {
+	if a.TouchMode == TouchProbe {
+		a.DragProbeCheck(s)
+		return nil
+	}
+	if a.TouchMode == TouchSource {
+		a.PaintSourceCheck(s)
+		return nil
+	}
+	if a.TouchMode == TouchPorous {
+		a.PaintPorousCheck(s)
+		return nil
+	}
+	var drag *lbm.DragFluidProperties
+	if a.TouchHandler.TouchDrag {
+		if a.OldTouchX >= 0 {
+			// The texture is rotated by 90 deg
+			gy, gx := a.TouchToGrid()
+			pushUX := (a.TouchHandler.TouchX - a.OldTouchX) / float32(a.PxPerSimSquare) / float32(s.StepsPerFrame())
+			pushUY := -(a.TouchHandler.TouchY - a.OldTouchY) / float32(a.PxPerSimSquare) / float32(s.StepsPerFrame()) // y axis is flipped
+			if math.Abs(float64(pushUX)) > 0.1 {
+				pushUX = 0.1
+			}
+			if math.Abs(float64(pushUY)) > 0.1 {
+				pushUY = 0.1
+			}
+			drag = &lbm.DragFluidProperties{PushX: gx, PushY: gy, PushUX: pushUX, PushUY: pushUY}
+		}
+		a.OldTouchX = a.TouchHandler.TouchX
+		a.OldTouchY = a.TouchHandler.TouchY
+	} else {
+		a.OldTouchX = -1
+		a.OldTouchY = -1
+	}
+	return drag
+}
+
+// DragProbeCheck moves the probe under the touch while the user drags it
+func (a *AppProperties) DragProbeCheck(s *lbm.Solver) {
+	if !a.TouchHandler.TouchDrag {
+		a.DragProbe = -1
+		return
+	}
+	// The texture is rotated by 90 deg
+	gy, gx := a.TouchToGrid()
+	if a.DragProbe < 0 {
+		a.DragProbe = s.ProbeAt(gx, gy, 4)
+	}
+	if a.DragProbe >= 0 {
+		s.MoveProbe(a.DragProbe, gx, gy)
+	}
+}
+
+// PaintSourceCheck turns the fluid under the touch into dye sources while
+// the user drags over it
+func (a *AppProperties) PaintSourceCheck(s *lbm.Solver) {
+	if !a.TouchHandler.TouchDrag {
+		return
+	}
+	// The texture is rotated by 90 deg
+	gy, gx := a.TouchToGrid()
+	for y := gy - 1; y <= gy+1; y++ {
+		for x := gx - 1; x <= gx+1; x++ {
+			if x >= 0 && x < s.Xdim() && y >= 0 && y < s.Ydim() && s.Cell(x, y) == lbm.FluidCell {
+				s.SetCell(x, y, lbm.SourceCell)
+			}
+		}
+	}
+}
+
+// PaintPorousCheck gives the fluid under the touch the selected solid
+// fraction while the user drags over it
+func (a *AppProperties) PaintPorousCheck(s *lbm.Solver) {
+	if !a.TouchHandler.TouchDrag {
+		return
+	}
+	// The texture is rotated by 90 deg
+	gy, gx := a.TouchToGrid()
+	for y := gy - 1; y <= gy+1; y++ {
+		for x := gx - 1; x <= gx+1; x++ {
+			if x < 0 || x >= s.Xdim() || y < 0 || y >= s.Ydim() {
+				continue
+			}
+			if c := s.Cell(x, y); c == lbm.FluidCell || c == lbm.PorousCell {
+				s.SetSolidFraction(x, y, solidFractions[a.Porosity])
+			}
+		}
+	}
+}
+
+// TapProbe removes the probe under a tap, or places a new one
+func (a *AppProperties) TapProbe(s *lbm.Solver) {
+	gy, gx := a.TouchToGrid()
+	if id := s.ProbeAt(gx, gy, 4); id >= 0 {
+		s.RemoveProbe(id)
+	} else {
+		s.AddProbe(gx, gy)
+	}
+}
+
+// ExportProbes writes the probe time series to probes.csv
+func (a *AppProperties) ExportProbes() {
+	f, err := os.Create("probes.csv")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := solver.WriteProbeCSV(f); err != nil {
+		fmt.Println(err)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Wrote", solver.NumProbes(), "probes to probes.csv")
 }
 
 // UpdateDeviceSpecs updates the device specifications based on new data
@@ -114,6 +349,12 @@ func (a *AppProperties) HandleTouchEvent(e touch.Event) {
 
 	timeNow := gotime.Now()
 
+	if e.Type == touch.TypeBegin {
+		// taps on the menu and the bottom bar are handled by their widgets
+		barTop := (1 - bottomBarTop) / 2 * float32(a.Device.ScreenDim[uiengine.Y])
+		a.ProbeTap = a.TouchMode == TouchProbe && !a.ShowMenu && e.Y < barTop
+	}
+
 	// Forward any clicks to the touch handler
 	if (e.Type == touch.TypeBegin) && a.TouchHandler.LongTouch {
 		if !a.TouchHandler.ProcessingClick {
@@ -136,6 +377,11 @@ func (a *AppProperties) HandleTouchEvent(e touch.Event) {
 	}
 
 	if e.Type == touch.TypeEnd {
+		short := timeNow.Sub(a.TouchHandler.LongTouchPrevTime) < 500*gotime.Millisecond
+		if a.ProbeTap && short && !a.TouchHandler.TouchDrag {
+			a.TouchHandler.TouchX, a.TouchHandler.TouchY = e.X, e.Y
+			a.TapProbe(solver)
+		}
 		a.TouchHandler.LongTouchMeasure = false
 		a.TouchHandler.TouchDrag = false
 	}
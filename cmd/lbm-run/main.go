@@ -0,0 +1,560 @@
+// Command lbm-run runs the lattice-Boltzmann solver without a display and
+// writes the results to disk. It is meant for batch runs on build servers.
+//
+// Usage:
+//
+//	lbm-run -x 256 -y 128 -vel 0.1 -visc 0.02 -barrier circle -steps 6000 -out results
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prasadchandan/go_lbm/lbm"
+)
+
+func main() {
+	xdim := flag.Int("x", 128, "number of lattice sites along x")
+	ydim := flag.Int("y", 64, "number of lattice sites along y")
+	vel := flag.Float64("vel", 0.1, "inflow velocity in lattice units")
+	visc := flag.Float64("visc", 0.03, "kinematic viscosity in lattice units")
+	barrier := flag.String("barrier", "line", "barrier type: line, circle, cavity, benard, heated, empty, drop, phases, fingers or porous")
+	collision := flag.String("collision", "bgk", "collision operator: "+strings.Join(lbm.CollisionOperatorNames(), ", "))
+	magic := flag.Float64("magic", 0.25, "magic parameter of the trt collision operator, 3/16 puts walls exactly half way for Poiseuille flow")
+	mrtBulk := flag.Float64("mrt-bulk", 1.64, "relaxation rate of the mrt energy moment, sets the bulk viscosity")
+	mrtEnergy := flag.Float64("mrt-energy", 1.54, "relaxation rate of the mrt energy squared moment")
+	mrtGhost := flag.Float64("mrt-ghost", 1.9, "relaxation rate of the mrt energy flux (ghost) moments")
+	steps := flag.Int("steps", 3000, "number of time steps to run")
+	stepsPerFrame := flag.Int("spf", 3, "time steps per frame")
+	var edges [lbm.NumEdges]*string
+	edges[lbm.Left] = flag.String("left", "inlet", "left boundary: inlet, outlet, wall, slip, periodic, zh-inlet, zh-outlet, moving, hot or cold")
+	edges[lbm.Right] = flag.String("right", "outlet", "right boundary")
+	edges[lbm.Bottom] = flag.String("bottom", "inlet", "bottom boundary")
+	edges[lbm.Top] = flag.String("top", "inlet", "top boundary")
+	var densities [lbm.NumEdges]*float64
+	for edge, name := range []string{"left", "right", "bottom", "top"} {
+		densities[edge] = flag.Float64(name+"-rho", 1, "density imposed by a pressure boundary on the "+name+" edge")
+	}
+	cs := flag.Float64("cs", 0, "Smagorinsky constant, 0 disables the LES subgrid model")
+	fx := flag.Float64("fx", 0, "uniform force density along x driving the flow")
+	fy := flag.Float64("fy", 0, "uniform force density along y driving the flow")
+	interp := flag.Bool("interp", true, "interpolated bounce-back on curved barriers, false puts every wall halfway between sites")
+	spin := flag.Float64("spin", 0, "surface speed of the barrier over the inflow velocity, positive spins counter-clockwise")
+	tracers := flag.String("tracers", "off", "tracer particles: off, grid or inlet")
+	tracerSpacing := flag.Int("tracer-spacing", 4, "sites between seeded tracers")
+	stokes := flag.Float64("stokes", 0, "Stokes number of the tracers, 0 follows the flow exactly")
+	lines := flag.Int("streamlines", 0, "draw streamlines seeded every n sites, 0 draws none")
+	lineColor := flag.String("streamline-color", "ffffff", "streamline color as RRGGBB")
+	var probes probeList
+	flag.Var(&probes, "probe", "record the flow at the site x,y every step, may be repeated")
+	dyeDiff := flag.Float64("dye-diff", 0, "diffusivity of a passive dye carried by the flow, 0 disables the dye")
+	dyeInlet := flag.Float64("dye-inlet", 0, "dye concentration entering through the inlets")
+	dyeSource := flag.Float64("dye-source", 1, "dye concentration held at the sources")
+	ra := flag.Float64("ra", 0, "Rayleigh number of the buoyancy driven flow, 0 disables the temperature field")
+	pr := flag.Float64("pr", 0.71, "Prandtl number of the temperature field")
+	interaction := flag.Float64("g", 0, "Shan-Chen interaction strength, below -4 the fluid separates into liquid and vapour, 0 disables it")
+	angle := flag.Float64("contact-angle", 90, "contact angle in degrees of the liquid on the barriers and walls")
+	visc2 := flag.Float64("visc2", 0, "kinematic viscosity of a second immiscible fluid filling the domain, 0 keeps a single fluid")
+	tension := flag.Float64("tension", 0.005, "surface tension between the two immiscible fluids")
+	solidFraction := flag.Float64("solid-fraction", 0.05, "solid fraction of the porous block, 0 lets the fluid through freely and 1 holds it at rest")
+	porosity := flag.String("porosity", "", "PNG image stretched over the grid whose dark pixels paint porous sites, black holding the fluid at rest")
+	var sources sourceList
+	flag.Var(&sources, "source", "release dye from a disc x,y,r of fluid sites, may be repeated")
+	plot := flag.Int("plot", lbm.PlotCurl, "plotted quantity: 0 rho, 1 ux, 2 uy, 3 speed, 4 curl, 5 viscosity, 6 dye, 7 temperature, 8 phase")
+	every := flag.Int("every", 0, "write an image every n frames, 0 writes only the final frame")
+	out := flag.String("out", "lbm-out", "output directory")
+	flag.Parse()
+
+	barrierType, err := parseBarrier(*barrier)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tracerMode, err := parseTracers(*tracers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(*lineColor, "%02x%02x%02x", &r, &g, &b); err != nil {
+		log.Fatalf("streamline color %q is not RRGGBB", *lineColor)
+	}
+	op, err := lbm.NewCollisionOperator(*collision)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if trt, ok := op.(*lbm.TRT); ok {
+		if *magic <= 0 {
+			log.Fatal("the magic parameter must be positive")
+		}
+		trt.Magic = float32(*magic)
+	}
+	if mrt, ok := op.(*lbm.MRT); ok {
+		for _, rate := range []float64{*mrtBulk, *mrtEnergy, *mrtGhost} {
+			if rate <= 0 || rate >= 2 {
+				log.Fatal("the mrt relaxation rates must lie between 0 and 2")
+			}
+		}
+		mrt.SBulk = float32(*mrtBulk)
+		mrt.SEnergy = float32(*mrtEnergy)
+		mrt.SGhost = float32(*mrtGhost)
+	}
+	var boundaries [lbm.NumEdges]lbm.BoundaryType
+	for edge, name := range edges {
+		if boundaries[edge], err = lbm.ParseBoundaryType(*name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for edge, typ := range boundaries {
+		if (typ == lbm.Periodic) != (boundaries[lbm.OppositeEdge[edge]] == lbm.Periodic) {
+			log.Fatal("periodic boundaries must be set on both opposite edges")
+		}
+	}
+	if *stepsPerFrame < 1 {
+		log.Fatal("steps per frame must be at least 1")
+	}
+	if *xdim < 3 || *ydim < 3 {
+		log.Fatal("the grid must be at least 3 sites along x and y")
+	}
+	if *visc <= 0 {
+		log.Fatal("the viscosity must be positive")
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	solver := lbm.CreateSolver(*xdim, *ydim, float32(*vel), float32(*visc))
+	solver.SetInterpolatedBounceBack(*interp)
+	if *ra > 0 {
+		solver.EnableThermal(float32(*ra), float32(*pr))
+	}
+	if *interaction != 0 {
+		solver.EnableShanChen(float32(*interaction))
+		solver.SetContactAngle(float32(*angle))
+	}
+	if *visc2 > 0 {
+		solver.EnableImmiscible(float32(*visc2), float32(*tension))
+	}
+	for edge, typ := range boundaries {
+		solver.SetBoundary(edge, typ)
+		solver.SetBoundaryDensity(edge, float32(*densities[edge]))
+	}
+	solver.SetPorousFraction(float32(*solidFraction))
+	solver.InitalizeLattice(*xdim, *ydim, float32(*vel), float32(*visc), barrierType)
+	if *porosity != "" {
+		if err := loadPorosity(solver, *porosity); err != nil {
+			log.Fatal(err)
+		}
+	}
+	solver.SetStepsPerFrame(*stepsPerFrame)
+	solver.SetCollisionOperator(op)
+	solver.SetSmagorinskyConstant(float32(*cs))
+	solver.SetExternalForce(float32(*fx), float32(*fy))
+	solver.SeedTracers(tracerMode, *tracerSpacing)
+	solver.SetStreamlines(*lines, color.RGBA{r, g, b, 255})
+	solver.SetTracerStokes(float32(*stokes))
+	for _, p := range probes {
+		if solver.AddProbe(p[0], p[1]) < 0 {
+			log.Fatalf("probe %d,%d is outside the grid", p[0], p[1])
+		}
+	}
+	for id := 0; id < solver.NumBodies(); id++ {
+		solver.SetBodySpinRatio(id, float32(*spin))
+	}
+	if *dyeDiff > 0 {
+		solver.EnableScalar(float32(*dyeDiff))
+		solver.SetScalarInlet(float32(*dyeInlet))
+		solver.SetScalarSource(float32(*dyeSource))
+		for _, src := range sources {
+			paintSource(solver, src)
+		}
+	}
+
+	frames := *steps / *stepsPerFrame
+	for frame := 1; frame <= frames; frame++ {
+		solver.Simulate(nil)
+		if *every > 0 && frame%*every == 0 {
+			name := filepath.Join(*out, fmt.Sprintf("frame_%06d.png", frame))
+			if err := writeImage(solver, *plot, name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	// steps left over from the last whole frame
+	for step := 0; step < *steps%*stepsPerFrame; step++ {
+		solver.Step()
+	}
+
+	if err := writeImage(solver, *plot, filepath.Join(*out, "final.png")); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeFields(solver, filepath.Join(*out, "fields.csv")); err != nil {
+		log.Fatal(err)
+	}
+	if barrierType == lbm.CAVITY {
+		if err := writeCenterlines(solver, filepath.Join(*out, "centerlines.csv")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *fx != 0 && solver.PeriodicX() && solver.Boundary(lbm.Bottom) == lbm.NoSlipWall && solver.Boundary(lbm.Top) == lbm.NoSlipWall {
+		if err := writePoiseuille(solver, float32(*fx), filepath.Join(*out, "poiseuille.csv")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for edge, name := range []string{"left", "right", "bottom", "top"} {
+		switch solver.Boundary(edge) {
+		case lbm.VelocityInlet, lbm.PressureOutlet, lbm.ZouHeVelocity, lbm.ZouHePressure:
+			fmt.Printf("Mass flux through the %s edge: %g\n", name, solver.MassFlux(edge))
+		}
+	}
+	if solver.ThermalEnabled() {
+		fmt.Printf("Nusselt number of the hot walls: %.4f at Ra %g, Pr %g\n", solver.NusseltNumber(), *ra, *pr)
+	}
+	if solver.ShanChenEnabled() {
+		vapour, liquid := solver.CoexistenceDensities()
+		v, l := phaseDensities(solver)
+		fmt.Printf("Vapour density %.4f, liquid density %.4f, predicted %.4f and %.4f at G %g\n", v, l, vapour, liquid, *interaction)
+	}
+	if dp, ok := solver.PorousPressureDrop(); ok {
+		fmt.Printf("Pressure drop across the porous sites: %g\n", dp)
+	}
+	if solver.ImmiscibleEnabled() {
+		fill, front := firstFluidExtent(solver)
+		fmt.Printf("First fluid fills %.1f%% of the domain, its front is at x = %d\n", 100*fill, front)
+	}
+	for id := 0; id < solver.NumBodies(); id++ {
+		f := solver.BodyForce(id)
+		fmt.Printf("Body %d: Fx %g Fy %g torque %g Cd %.4f Cl %.4f\n", id, f.Fx, f.Fy, f.Torque, f.Cd, f.Cl)
+		if st, ok := solver.StrouhalNumber(id); ok {
+			freq, _ := solver.SheddingFrequency(id)
+			fmt.Printf("Body %d: shedding period %.1f steps, St %.4f\n", id, 1/freq, st)
+		}
+	}
+	if solver.NumProbes() > 0 {
+		if err := writeProbes(solver, filepath.Join(*out, "probes.csv")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if solver.NumBodies() > 0 {
+		if err := writeLift(solver, filepath.Join(*out, "lift.csv")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("Ran %d steps on a %dx%d grid at Re %.1f, results in %s\n", solver.Time(), *xdim, *ydim, solver.ReynoldsNumber(), *out)
+}
+
+// probeList collects the sites given with the -probe flag
+type probeList [][2]int
+
+func (p *probeList) String() string {
+	return fmt.Sprint(*p)
+}
+
+func (p *probeList) Set(v string) error {
+	var x, y int
+	if _, err := fmt.Sscanf(v, "%d,%d", &x, &y); err != nil {
+		return fmt.Errorf("probe %q is not x,y", v)
+	}
+	*p = append(*p, [2]int{x, y})
+	return nil
+}
+
+// sourceList collects the discs given with the -source flag
+type sourceList [][3]int
+
+func (p *sourceList) String() string {
+	return fmt.Sprint(*p)
+}
+
+func (p *sourceList) Set(v string) error {
+	var x, y, r int
+	if _, err := fmt.Sscanf(v, "%d,%d,%d", &x, &y, &r); err != nil {
+		return fmt.Errorf("source %q is not x,y,r", v)
+	}
+	*p = append(*p, [3]int{x, y, r})
+	return nil
+}
+
+// paintSource turns the fluid sites of a disc into dye sources
+func paintSource(s *lbm.Solver, src [3]int) {
+	r := src[2]
+	for y := src[1] - r; y <= src[1]+r; y++ {
+		for x := src[0] - r; x <= src[0]+r; x++ {
+			dx, dy := x-src[0], y-src[1]
+			if dx*dx+dy*dy > r*r || x < 0 || x >= s.Xdim() || y < 0 || y >= s.Ydim() {
+				continue
+			}
+			if s.Cell(x, y) == lbm.FluidCell {
+				s.SetCell(x, y, lbm.SourceCell)
+			}
+		}
+	}
+}
+
+func parseBarrier(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "line":
+		return lbm.LINE, nil
+	case "circle":
+		return lbm.CIRCLE, nil
+	case "cavity":
+		return lbm.CAVITY, nil
+	case "benard":
+		return lbm.BENARD, nil
+	case "heated":
+		return lbm.HEATED, nil
+	case "empty":
+		return lbm.EMPTY, nil
+	case "drop":
+		return lbm.DROP, nil
+	case "phases":
+		return lbm.PHASES, nil
+	case "fingers":
+		return lbm.FINGERS, nil
+	case "porous":
+		return lbm.POROUS, nil
+	}
+	return 0, fmt.Errorf("unknown barrier type %q", name)
+}
+
+func parseTracers(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "off":
+		return lbm.TracersOff, nil
+	case "grid":
+		return lbm.TracersGrid, nil
+	case "inlet":
+		return lbm.TracersInlet, nil
+	}
+	return 0, fmt.Errorf("unknown tracer mode %q", name)
+}
+
+// Mean densities of the fluid sites closer to the vapour and to the liquid
+// coexistence densities
+func phaseDensities(s *lbm.Solver) (float32, float32) {
+	vapour, liquid := s.CoexistenceDensities()
+	mid := (vapour + liquid) / 2
+	var sum [2]float32
+	var n [2]int
+	rho := s.Rho()
+	for y := 0; y < s.Ydim(); y++ {
+		for x := 0; x < s.Xdim(); x++ {
+			if s.Cell(x, y) != lbm.FluidCell {
+				continue
+			}
+			r := rho[s.Index(x, y)]
+			k := 0
+			if r > mid {
+				k = 1
+			}
+			sum[k] += r
+			n[k]++
+		}
+	}
+	for k := range sum {
+		if n[k] > 0 {
+			sum[k] /= float32(n[k])
+		}
+	}
+	return sum[0], sum[1]
+}
+
+// loadPorosity paints the porous sites from a PNG image
+func loadPorosity(s *lbm.Solver, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	m, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("porosity image %s: %v", name, err)
+	}
+	s.LoadPorosity(m)
+	return nil
+}
+
+// Fraction of the fluid sites held by the first immiscible fluid, and the
+// column furthest downstream it reaches
+func firstFluidExtent(s *lbm.Solver) (float32, int) {
+	phase := s.Phase()
+	var first, fluid, front int
+	for y := 0; y < s.Ydim(); y++ {
+		for x := 0; x < s.Xdim(); x++ {
+			if s.Cell(x, y).Solid() {
+				continue
+			}
+			fluid++
+			if phase[s.Index(x, y)] > 0 {
+				first++
+				front = max(front, x)
+			}
+		}
+	}
+	if fluid == 0 {
+		return 0, 0
+	}
+	return float32(first) / float32(fluid), front
+}
+
+// writeImage plots the selected flow property to a PNG file
+func writeImage(s *lbm.Solver, plotType int, name string) error {
+	m := image.NewRGBA(image.Rect(0, 0, s.Xdim(), s.Ydim()))
+	s.PlotToImage(m, plotType)
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFields dumps the macroscopic fields of every lattice site as CSV
+func writeFields(s *lbm.Solver, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	rho, ux, uy, curl := s.Rho(), s.Ux(), s.Uy(), s.Curl()
+	visc := s.EffectiveViscosity()
+	dye, temp, phase := s.Scalar(), s.Temperature(), s.Phase()
+	fmt.Fprintln(w, "x,y,cell,rho,ux,uy,curl,visc,dye,temperature,phase")
+	for y := 0; y < s.Ydim(); y++ {
+		for x := 0; x < s.Xdim(); x++ {
+			i := s.Index(x, y)
+			var c, t, p float32
+			if dye != nil {
+				c = dye[i]
+			}
+			if temp != nil {
+				t = temp[i]
+			}
+			if phase != nil {
+				p = phase[i]
+			}
+			fmt.Fprintf(w, "%d,%d,%s,%g,%g,%g,%g,%g,%g,%g,%g\n", x, y, s.Cell(x, y), rho[i], ux[i], uy[i], curl[i], visc[i], c, t, p)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeCenterlines writes the velocity profiles through the middle of a
+// lid-driven cavity, normalized by the lid velocity, for comparison with
+// Ghia et al. The walls are half way between the edge sites and the fluid.
+func writeCenterlines(s *lbm.Solver, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	ux, uy := s.Ux(), s.Uy()
+	lid := s.BoundaryVelocity(lbm.Top)
+	xc, yc := s.Xdim()/2, s.Ydim()/2
+	fmt.Fprintln(w, "line,pos,velocity")
+	// u along the vertical centerline, averaging the columns either side of it
+	for y := 1; y < s.Ydim()-1; y++ {
+		u := (ux[s.Index(xc-1, y)] + ux[s.Index(xc, y)]) / 2
+		fmt.Fprintf(w, "u_vertical,%g,%g\n", (float32(y)-0.5)/float32(s.Ydim()-2), u/lid)
+	}
+	// v along the horizontal centerline
+	for x := 1; x < s.Xdim()-1; x++ {
+		v := (uy[s.Index(x, yc-1)] + uy[s.Index(x, yc)]) / 2
+		fmt.Fprintf(w, "v_horizontal,%g,%g\n", (float32(x)-0.5)/float32(s.Xdim()-2), v/lid)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writePoiseuille writes the velocity profile across a periodic channel
+// driven by the force density fx, averaged along x, next to the analytic
+// Poiseuille profile fx (y - y0)(y1 - y) / (2 rho nu) between the walls half
+// way between the edge sites and the fluid, and prints the largest error
+// relative to the centerline velocity
+func writePoiseuille(s *lbm.Solver, fx float32, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	rho, ux := s.Rho(), s.Ux()
+	y0, y1 := float32(0.5), float32(s.Ydim())-1.5
+	var mass float32
+	for y := 1; y < s.Ydim()-1; y++ {
+		for x := 0; x < s.Xdim(); x++ {
+			mass += rho[s.Index(x, y)]
+		}
+	}
+	mean := mass / float32(s.Xdim()*(s.Ydim()-2))
+	umax := fx * (y1 - y0) * (y1 - y0) / (8 * mean * s.FlowViscosity())
+	var maxErr float32
+	fmt.Fprintln(w, "y,ux,analytic")
+	for y := 1; y < s.Ydim()-1; y++ {
+		var u float32
+		for x := 0; x < s.Xdim(); x++ {
+			u += ux[s.Index(x, y)]
+		}
+		u /= float32(s.Xdim())
+		exact := fx * (float32(y) - y0) * (y1 - float32(y)) / (2 * mean * s.FlowViscosity())
+		maxErr = max(maxErr, float32(math.Abs(float64(u-exact))))
+		fmt.Fprintf(w, "%d,%g,%g\n", y, u, exact)
+	}
+	fmt.Printf("Poiseuille profile: centerline velocity %g, largest error %.3g%% of it\n", umax, 100*maxErr/umax)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeLift dumps the lift history of every body to a CSV file
+func writeLift(s *lbm.Solver, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "step,body,fy")
+	for id := 0; id < s.NumBodies(); id++ {
+		h := s.LiftHistory(id)
+		for i, fy := range h {
+			fmt.Fprintf(w, "%d,%d,%g\n", s.Time()-len(h)+i+1, id, fy)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeProbes dumps the time series recorded by the probes to a CSV file
+func writeProbes(s *lbm.Solver, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteProbeCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,147 @@
+package lbm
+
+// Body is a rigid barrier. Its walls may translate and rotate about its
+// center, the barrier sites themselves stay in place and only the velocity
+// the walls impart on the fluid changes.
+type Body struct {
+	// Center of rotation, in lattice units
+	CenterX float32
+	CenterY float32
+
+	// Extent across the flow, in lattice units
+	Size float32
+
+	// Translation velocity of the walls
+	Ux float32
+	Uy float32
+
+	// Rotation rate in radians per time step, counter-clockwise
+	Omega float32
+
+	// Exact geometry, nil for bodies painted site by site
+	Shape Shape
+
+	// Insulated, Heated or Cooled. Heated and cooled walls do not move.
+	Heat int
+}
+
+// Wall temperatures of bodies
+const (
+	Insulated = iota // no heat flows through the walls
+	Heated           // walls held at the hot temperature
+	Cooled           // walls held at the cold temperature
+)
+
+// WallVelocity returns the velocity of the body surface at the point (x, y)
+func (b *Body) WallVelocity(x, y float32) (float32, float32) {
+	return b.Ux - b.Omega*(y-b.CenterY), b.Uy + b.Omega*(x-b.CenterX)
+}
+
+// Moving reports whether the walls of the body move
+func (b *Body) Moving() bool {
+	return b.Ux != 0 || b.Uy != 0 || b.Omega != 0
+}
+
+// AddBody registers a new body and returns its ID
+func (s *Solver) AddBody(b Body) int {
+	s.bodies = append(s.bodies, b)
+	return len(s.bodies) - 1
+}
+
+// NumBodies returns the number of bodies
+func (s *Solver) NumBodies() int {
+	return len(s.bodies)
+}
+
+// Body returns the body with the given ID
+func (s *Solver) Body(id int) Body {
+	return s.bodies[id]
+}
+
+// SetBody replaces the body with the given ID
+func (s *Solver) SetBody(id int, b Body) {
+	s.bodies[id] = b
+	s.markBody(id)
+}
+
+// SetBodyVelocity sets the translation velocity and the rotation rate, in
+// radians per time step, of the body with the given ID
+func (s *Solver) SetBodyVelocity(id int, ux, uy, omega float32) {
+	b := &s.bodies[id]
+	moving := b.Moving()
+	b.Ux = ux
+	b.Uy = uy
+	b.Omega = omega
+	if b.Moving() != moving {
+		s.markBody(id)
+	}
+}
+
+// SetBodySpinRatio spins the body so that its surface speed is ratio times
+// the inflow velocity, counter-clockwise for positive ratios
+func (s *Solver) SetBodySpinRatio(id int, ratio float32) {
+	b := s.bodies[id]
+	if b.Size <= 0 {
+		ratio = 0
+	} else {
+		ratio *= s.flowVel / (b.Size / 2)
+	}
+	s.SetBodyVelocity(id, b.Ux, b.Uy, ratio)
+}
+
+// BarrierBody returns the ID of the body the site (x, y) belongs to, or -1
+// if it does not belong to a body
+func (s *Solver) BarrierBody(x, y int) int {
+	return s.cellBody[x+y*s.xdim]
+}
+
+// SetBodyBarrier adds a barrier at the site (x, y) that belongs to the body
+// with the given ID, sites on the edge of the grid are left untouched
+func (s *Solver) SetBodyBarrier(x, y, id int) {
+	if x < 1 || x > s.xdim-2 || y < 1 || y > s.ydim-2 {
+		return
+	}
+	s.cells[x+y*s.xdim] = s.bodyCell(id)
+	s.cellBody[x+y*s.xdim] = id
+	s.linksValid = false
+}
+
+// Cell type of the sites of a body, after its temperature and velocity
+func (s *Solver) bodyCell(id int) CellType {
+	b := &s.bodies[id]
+	switch {
+	case b.Heat == Heated:
+		return HotWallCell
+	case b.Heat == Cooled:
+		return ColdWallCell
+	case b.Moving():
+		return MovingWallCell
+	}
+	return WallCell
+}
+
+// Type the sites of a body as moving, stationary, hot or cold walls
+func (s *Solver) markBody(id int) {
+	c := s.bodyCell(id)
+	for i, b := range s.cellBody {
+		if b == id {
+			s.cells[i] = c
+		}
+	}
+	s.linksValid = false
+}
+
+// Add the momentum of the moving walls of the barrier at (x, y) to the
+// populations bounced back into the neighbouring fluid sites. The wall
+// velocity is taken halfway along each link.
+func (s *Solver) moveWalls(x, y int, b *Body) {
+	pops := s.populations()
+	for q := 1; q < Q; q++ {
+		n := x + int(Cx[q]) + (y+int(Cy[q]))*s.xdim
+		if s.cells[n].Solid() {
+			continue
+		}
+		wx, wy := b.WallVelocity(float32(x)+0.5*Cx[q], float32(y)+0.5*Cy[q])
+		(*pops[q])[n] += 6 * W[q] * s.rho[n] * (Cx[q]*wx + Cy[q]*wy)
+	}
+}
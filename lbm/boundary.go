@@ -0,0 +1,386 @@
+package lbm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edges of the domain
+const (
+	Left = iota
+	Right
+	Bottom
+	Top
+	NumEdges
+)
+
+// BoundaryType selects the boundary condition applied on an edge
+type BoundaryType int
+
+// Boundary conditions. The cells on the edges of the grid are not collided,
+// they are refilled after every collision so that streaming pulls the
+// populations required by the condition of their edge.
+const (
+	// Equilibrium at unit density moving with the flow velocity along x
+	VelocityInlet BoundaryType = iota
+	// Equilibrium at the edge density, moving with the velocity next to the edge
+	PressureOutlet
+	// Stationary wall with half-way bounce-back
+	NoSlipWall
+	// Symmetry plane, populations are reflected specularly
+	FreeSlipWall
+	// Populations leaving through the edge re-enter through the opposite edge
+	Periodic
+	// Zou-He non-equilibrium bounce-back on the cells next to the edge,
+	// imposing the flow velocity along x
+	ZouHeVelocity
+	// Zou-He non-equilibrium bounce-back on the cells next to the edge,
+	// imposing the edge density
+	ZouHePressure
+	// Wall sliding along the edge with the edge velocity, bounce-back with
+	// the momentum the wall transfers to the fluid
+	MovingWall
+	// Stationary wall held at the hot temperature
+	HotWall
+	// Stationary wall held at the cold temperature
+	ColdWall
+	NumBoundaryTypes
+)
+
+var boundaryNames = [NumBoundaryTypes]string{"inlet", "outlet", "wall", "slip", "periodic", "zh-inlet", "zh-outlet", "moving", "hot", "cold"}
+
+func (b BoundaryType) String() string {
+	if b < 0 || b >= NumBoundaryTypes {
+		return fmt.Sprintf("BoundaryType(%d)", int(b))
+	}
+	return boundaryNames[b]
+}
+
+// ParseBoundaryType returns the boundary type with the given name, as
+// returned by BoundaryType.String
+func ParseBoundaryType(name string) (BoundaryType, error) {
+	for b, n := range boundaryNames {
+		if strings.EqualFold(n, name) {
+			return BoundaryType(b), nil
+		}
+	}
+	return 0, fmt.Errorf("lbm: unknown boundary type %q", name)
+}
+
+// edgeBoundary holds the boundary condition of one edge
+type edgeBoundary struct {
+	typ      BoundaryType
+	density  float32
+	velocity float32
+}
+
+// Inward normal of each edge
+var (
+	edgeNx = [NumEdges]int{1, -1, 0, 0}
+	edgeNy = [NumEdges]int{0, 0, 1, -1}
+)
+
+// OppositeEdge maps each edge to the edge on the other side of the domain
+var OppositeEdge = [NumEdges]int{Right, Left, Top, Bottom}
+
+// Mirror maps each direction to its reflection on a wall normal to x or to y
+var (
+	mirrorX = [Q]int{D0, DW, DN, DE, DS, DNW, DNE, DSE, DSW}
+	mirrorY = [Q]int{D0, DE, DS, DW, DN, DSE, DSW, DNW, DNE}
+)
+
+// SetDefaultBoundaries restores the wind tunnel, with inlets on the left,
+// bottom and top edges and a pressure outlet on the right edge
+func (s *Solver) SetDefaultBoundaries() {
+	for edge := 0; edge < NumEdges; edge++ {
+		s.boundaries[edge] = edgeBoundary{typ: VelocityInlet, density: 1}
+	}
+	s.boundaries[Right].typ = PressureOutlet
+	s.markEdges()
+}
+
+// SetBoundary sets the boundary condition of an edge. Periodic boundaries
+// should be set on both opposite edges.
+func (s *Solver) SetBoundary(edge int, typ BoundaryType) {
+	s.boundaries[edge].typ = typ
+	s.markEdges()
+}
+
+// Boundary returns the boundary condition of an edge
+func (s *Solver) Boundary(edge int) BoundaryType {
+	return s.boundaries[edge].typ
+}
+
+// SetBoundaryDensity sets the density imposed by a pressure outlet or a
+// Zou-He pressure boundary. Different densities on an inlet and an outlet
+// drive a pressure-driven flow.
+func (s *Solver) SetBoundaryDensity(edge int, rho float32) {
+	s.boundaries[edge].density = rho
+}
+
+// BoundaryDensity returns the density imposed by a pressure boundary
+func (s *Solver) BoundaryDensity(edge int) float32 {
+	return s.boundaries[edge].density
+}
+
+// Density held by outlet sites painted inside the domain, that of the first
+// pressure edge, or of the right edge if none
+func (s *Solver) outletDensity() float32 {
+	for edge := 0; edge < NumEdges; edge++ {
+		if typ := s.boundaries[edge].typ; typ == PressureOutlet || typ == ZouHePressure {
+			return s.boundaries[edge].density
+		}
+	}
+	return s.boundaries[Right].density
+}
+
+// PeriodicX reports whether the domain wraps around along x. The edge
+// columns of a periodic axis are ordinary fluid sites.
+func (s *Solver) PeriodicX() bool {
+	return s.boundaries[Left].typ == Periodic && s.boundaries[Right].typ == Periodic
+}
+
+// PeriodicY reports whether the domain wraps around along y
+func (s *Solver) PeriodicY() bool {
+	return s.boundaries[Bottom].typ == Periodic && s.boundaries[Top].typ == Periodic
+}
+
+// Range of columns holding fluid, which includes the edges when periodic
+func (s *Solver) xRange() (int, int) {
+	if s.PeriodicX() {
+		return 0, s.xdim - 1
+	}
+	return 1, s.xdim - 2
+}
+
+// Range of rows holding fluid, which includes the edges when periodic
+func (s *Solver) yRange() (int, int) {
+	if s.PeriodicY() {
+		return 0, s.ydim - 1
+	}
+	return 1, s.ydim - 2
+}
+
+// SetBoundaryVelocity sets the velocity of a moving wall, along +x for the
+// bottom and top edges and along +y for the left and right edges
+func (s *Solver) SetBoundaryVelocity(edge int, u float32) {
+	s.boundaries[edge].velocity = u
+}
+
+// BoundaryVelocity returns the velocity of a moving wall
+func (s *Solver) BoundaryVelocity(edge int) float32 {
+	return s.boundaries[edge].velocity
+}
+
+// SetCavityBoundaries sets up a lid-driven cavity, with the top wall
+// moving at the flow velocity and the other edges at rest
+func (s *Solver) SetCavityBoundaries() {
+	s.SetBoundary(Left, NoSlipWall)
+	s.SetBoundary(Right, NoSlipWall)
+	s.SetBoundary(Bottom, NoSlipWall)
+	s.SetBoundary(Top, MovingWall)
+	s.SetBoundaryVelocity(Top, s.flowVel)
+}
+
+// SetBoundaries sets the fluid variables at the boundaries. It is called
+// after every collision, edge cells parallel to y are filled first so the
+// corners follow the conditions of the bottom and top edges.
+func (s *Solver) SetBoundaries() {
+	if !s.PeriodicX() {
+		y0, y1 := s.yRange()
+		for y := y0; y <= y1; y++ {
+			s.setEdgeCell(Left, 0, y)
+			s.setEdgeCell(Right, s.xdim-1, y)
+		}
+	}
+	if !s.PeriodicY() {
+		for x := 0; x < s.xdim; x++ {
+			s.setEdgeCell(Bottom, x, 0)
+			s.setEdgeCell(Top, x, s.ydim-1)
+		}
+	}
+}
+
+// Fill the edge cell (x, y) of edge according to its cell type
+func (s *Solver) setEdgeCell(edge, x, y int) {
+	bc := s.boundaries[edge]
+	nx, ny := edgeNx[edge], edgeNy[edge]
+	i := x + y*s.xdim
+	in := x + nx + (y+ny)*s.xdim // neighbouring cell inside the domain
+
+	switch typ := s.cells[i]; typ {
+	case InletCell:
+		s.SetEquilibrium(x, y, s.flowVel, 0, 1)
+	case OutletCell:
+		s.SetEquilibrium(x, y, s.ux[in], s.uy[in], bc.density)
+	case FluidCell:
+		// only reached when the opposite edge is not periodic, copy
+		// the sites next to it
+		var f [Q]float32
+		src := x + nx*(s.xdim-2) + (y+ny*(s.ydim-2))*s.xdim
+		s.gather(src, &f)
+		s.scatter(i, &f)
+		s.rho[i], s.ux[i], s.uy[i] = s.rho[src], s.ux[src], s.uy[src]
+	case WallCell, SlipWallCell, MovingWallCell, HotWallCell, ColdWallCell:
+		var f, g [Q]float32
+		s.gather(i, &f)
+		mirror := &mirrorX
+		if ny != 0 {
+			mirror = &mirrorY
+		}
+		var uw, vw float32 // wall velocity
+		if typ == MovingWallCell {
+			if ny != 0 {
+				uw = bc.velocity
+			} else {
+				vw = bc.velocity
+			}
+		}
+		for q := 1; q < Q; q++ {
+			cx, cy := int(Cx[q]), int(Cy[q])
+			if cx*nx+cy*ny <= 0 {
+				// not pulled into the domain from this cell
+				continue
+			}
+			if typ != SlipWallCell {
+				// bounce back the population leaving the target cell towards the wall
+				tx, ty := x+cx, y+cy
+				if s.PeriodicX() {
+					tx = (tx + s.xdim) % s.xdim
+				}
+				if s.PeriodicY() {
+					ty = (ty + s.ydim) % s.ydim
+				}
+				if tx < 0 || tx >= s.xdim || ty < 0 || ty >= s.ydim {
+					continue
+				}
+				t := tx + ty*s.xdim
+				s.gather(t, &g)
+				f[q] = g[Opposite[q]] + 6*W[q]*s.rho[t]*(Cx[q]*uw+Cy[q]*vw)
+			} else {
+				// reflect the population leaving the neighbour towards the wall
+				s.gather(in, &g)
+				f[q] = g[mirror[q]]
+			}
+		}
+		s.scatter(i, &f)
+		s.ux[i], s.uy[i] = uw, vw
+	}
+}
+
+// ApplyZouHe reconstructs the populations entering the cells next to the
+// Zou-He edges from the imposed velocity or density. It is called after
+// streaming, the edges parallel to y are handled first.
+func (s *Solver) ApplyZouHe() {
+	for edge := 0; edge < NumEdges; edge++ {
+		typ := s.boundaries[edge].typ
+		if typ != ZouHeVelocity && typ != ZouHePressure {
+			continue
+		}
+		switch edge {
+		case Left, Right:
+			x := 1
+			if edge == Right {
+				x = s.xdim - 2
+			}
+			y0, y1 := s.yRange()
+			for y := y0; y <= y1; y++ {
+				s.zouHeCell(edge, x, y)
+			}
+		case Bottom, Top:
+			y := 1
+			if edge == Top {
+				y = s.ydim - 2
+			}
+			x0, x1 := s.xRange()
+			for x := x0; x <= x1; x++ {
+				s.zouHeCell(edge, x, y)
+			}
+		}
+	}
+}
+
+// Zou-He boundary on the cell (x, y) next to edge. The unknown populations,
+// those pointing into the domain, are set from their opposites plus a
+// correction that gives the imposed velocity or density.
+func (s *Solver) zouHeCell(edge, x, y int) {
+	i := x + y*s.xdim
+	if s.cells[i].Solid() {
+		return
+	}
+	bc := s.boundaries[edge]
+	nx, ny := float32(edgeNx[edge]), float32(edgeNy[edge])
+	tx, ty := -ny, nx // tangent to the edge
+
+	var f [Q]float32
+	s.gather(i, &f)
+
+	// Sum of the known populations, counting those leaving through the edge twice
+	var known, fPos, fNeg float32
+	for q := 0; q < Q; q++ {
+		cn := Cx[q]*nx + Cy[q]*ny
+		if cn == 0 {
+			known += f[q]
+			if ct := Cx[q]*tx + Cy[q]*ty; ct > 0 {
+				fPos = f[q]
+			} else if ct < 0 {
+				fNeg = f[q]
+			}
+		} else if cn < 0 {
+			known += 2 * f[q]
+		}
+	}
+
+	var rho, un, ut float32
+	if bc.typ == ZouHeVelocity {
+		un = s.flowVel * nx
+		ut = s.flowVel * tx
+		rho = known / (1 - un)
+	} else {
+		rho = bc.density
+		un = 1 - known/rho
+	}
+
+	for q := 1; q < Q; q++ {
+		if Cx[q]*nx+Cy[q]*ny <= 0 {
+			continue
+		}
+		ct := Cx[q]*tx + Cy[q]*ty
+		if ct == 0 {
+			f[q] = f[Opposite[q]] + 2.0/3.0*rho*un
+		} else {
+			f[q] = f[Opposite[q]] - 0.5*ct*(fPos-fNeg) + 1.0/6.0*rho*un + 0.5*ct*rho*ut
+		}
+	}
+	s.scatter(i, &f)
+}
+
+// MassFlux returns the mass entering the domain through an edge per time
+// step, summed over the cells next to the edge
+func (s *Solver) MassFlux(edge int) float32 {
+	nx, ny := float32(edgeNx[edge]), float32(edgeNy[edge])
+	var flux float32
+	switch edge {
+	case Left, Right:
+		x := 1
+		if edge == Right {
+			x = s.xdim - 2
+		}
+		y0, y1 := s.yRange()
+		for y := y0; y <= y1; y++ {
+			i := x + y*s.xdim
+			flux += s.rho[i] * (s.ux[i]*nx + s.uy[i]*ny)
+		}
+	case Bottom, Top:
+		y := 1
+		if edge == Top {
+			y = s.ydim - 2
+		}
+		x0, x1 := s.xRange()
+		for x := x0; x <= x1; x++ {
+			i := x + y*s.xdim
+			flux += s.rho[i] * (s.ux[i]*nx + s.uy[i]*ny)
+		}
+	}
+	return flux
+}
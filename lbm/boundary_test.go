@@ -0,0 +1,115 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Linear interpolation of the samples v at the positions p, in ascending
+// order, to the position x
+func interpolate(p, v []float32, x float32) float32 {
+	for i := 1; i < len(p); i++ {
+		if x <= p[i] {
+			w := (x - p[i-1]) / (p[i] - p[i-1])
+			return v[i-1] + w*(v[i]-v[i-1])
+		}
+	}
+	return v[len(v)-1]
+}
+
+func TestLidDrivenCavityMatchesGhia(t *testing.T) {
+	const n, lid, re = 34, 0.1, 100
+	s := CreateSolver(n, n, lid, lid*(n-2)/re)
+	s.InitalizeLattice(n, n, lid, lid*(n-2)/re, CAVITY)
+	for step := 0; step < 10000; step++ {
+		s.Step()
+	}
+	// Centerline profiles scaled by the lid velocity, with the walls half
+	// way between the edge sites and the fluid
+	var pos, u, v []float32
+	for k := 1; k < n-1; k++ {
+		pos = append(pos, (float32(k)-0.5)/(n-2))
+		u = append(u, (s.ux[s.Index(n/2-1, k)]+s.ux[s.Index(n/2, k)])/(2*lid))
+		v = append(v, (s.uy[s.Index(k, n/2-1)]+s.uy[s.Index(k, n/2)])/(2*lid))
+	}
+	// Ghia, Ghia and Shin (1982), Re 100
+	tests := []struct {
+		line     string
+		pos, vel float32
+	}{
+		{"u", 0.9531, 0.68717},
+		{"u", 0.8516, 0.23151},
+		{"u", 0.7344, 0.00332},
+		{"u", 0.6172, -0.13641},
+		{"u", 0.5, -0.20581},
+		{"u", 0.4531, -0.21090},
+		{"u", 0.2813, -0.15662},
+		{"u", 0.1719, -0.10150},
+		{"u", 0.0625, -0.04192},
+		{"v", 0.9531, -0.08864},
+		{"v", 0.9063, -0.16914},
+		{"v", 0.8594, -0.22445},
+		{"v", 0.8047, -0.24533},
+		{"v", 0.5, 0.05454},
+		{"v", 0.2344, 0.17527},
+		{"v", 0.1563, 0.16077},
+		{"v", 0.0625, 0.09233},
+	}
+	for _, tt := range tests {
+		profile := u
+		if tt.line == "v" {
+			profile = v
+		}
+		got := interpolate(pos, profile, tt.pos)
+		if math.Abs(float64(got-tt.vel)) > 0.02 {
+			t.Errorf("%s at %g along the centerline: %g, want %g", tt.line, tt.pos, got, tt.vel)
+		}
+	}
+}
+
+// Run a channel between bounce-back walls at the bottom and the top with
+// Zou-He conditions on its ends to its steady state
+func zouHeChannel(left BoundaryType, vel, rhoIn, rhoOut, visc float32) *Solver {
+	const xdim, ydim = 40, 18
+	s := CreateSolver(xdim, ydim, vel, visc)
+	s.SetBoundary(Left, left)
+	s.SetBoundaryDensity(Left, rhoIn)
+	s.SetBoundary(Right, ZouHePressure)
+	s.SetBoundaryDensity(Right, rhoOut)
+	s.SetBoundary(Bottom, NoSlipWall)
+	s.SetBoundary(Top, NoSlipWall)
+	s.InitalizeLattice(xdim, ydim, vel, visc, EMPTY)
+	for step := 0; step < 8000; step++ {
+		s.Step()
+	}
+	return s
+}
+
+func TestZouHeMassFlux(t *testing.T) {
+	const visc = 0.1
+	tests := []struct {
+		name          string
+		left          BoundaryType
+		vel           float32
+		rhoIn, rhoOut float32
+		want          float32 // mass entering per step
+	}{
+		// the inlet imposes the flow velocity on the 16 rows of fluid
+		{"velocity inlet", ZouHeVelocity, 0.02, 1, 1, 16 * 0.02},
+		{"slow velocity inlet", ZouHeVelocity, 0.005, 1, 1, 16 * 0.005},
+		// Poiseuille flux H^3 G / (12 nu) for the pressure gradient
+		// G = (rhoIn - rhoOut) / 3 over the 37 sites between the Zou-He cells
+		{"pressure drop", ZouHePressure, 0, 1.003, 0.997, 16 * 16 * 16 * 0.006 / 3 / 37 / (12 * visc)},
+		{"small pressure drop", ZouHePressure, 0, 1.001, 0.999, 16 * 16 * 16 * 0.002 / 3 / 37 / (12 * visc)},
+	}
+	for _, tt := range tests {
+		s := zouHeChannel(tt.left, tt.vel, tt.rhoIn, tt.rhoOut, visc)
+		in, out := s.MassFlux(Left), -s.MassFlux(Right)
+		if math.Abs(float64(in-out)) > 1e-3*float64(tt.want) {
+			t.Errorf("%s: %g enters and %g leaves per step", tt.name, in, out)
+		}
+		if math.Abs(float64(in-tt.want)) > 0.03*float64(tt.want) {
+			t.Errorf("%s: mass flux %g, want %g", tt.name, in, tt.want)
+		}
+	}
+}
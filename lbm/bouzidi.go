@@ -0,0 +1,104 @@
+package lbm
+
+// A link between a fluid site and a barrier site. Links of bodies with an
+// exact shape are bounced back with the interpolation of Bouzidi, Firdaouss
+// and Lallemand (2001), the others sit halfway between the sites.
+type wallLink struct {
+	fluid int // fluid site
+	wall  int // barrier site
+	far   int // next site away from the wall, -1 falls back to half-way bounce-back
+	q     int // direction from the wall to the fluid site
+	body  int
+
+	// distance from the fluid site to the surface, as a fraction of the link
+	delta float32
+}
+
+// SetInterpolatedBounceBack selects the interpolated bounce-back for bodies
+// with an exact shape, otherwise all walls sit halfway between sites
+func (s *Solver) SetInterpolatedBounceBack(on bool) {
+	s.interpolate = on
+}
+
+func (s *Solver) InterpolatedBounceBack() bool {
+	return s.interpolate
+}
+
+// Find the links cut by the surface of the bodies
+func (s *Solver) buildLinks() {
+	s.links = s.links[:0]
+	x0, x1 := s.xRange()
+	y0, y1 := s.yRange()
+	inside := func(x, y int) bool {
+		return x >= x0 && x <= x1 && y >= y0 && y <= y1
+	}
+	for y := 1; y < s.ydim-1; y++ {
+		for x := 1; x < s.xdim-1; x++ {
+			wall := x + y*s.xdim
+			body := s.cellBody[wall]
+			if body < 0 {
+				continue
+			}
+			shape := s.bodies[body].Shape
+			for q := 1; q < Q; q++ {
+				fx, fy := x+int(Cx[q]), y+int(Cy[q])
+				fluid := fx + fy*s.xdim
+				if !inside(fx, fy) || s.cells[fluid].Solid() {
+					continue
+				}
+				// walk from the fluid site towards the barrier site
+				delta := float32(0.5)
+				if shape != nil {
+					if d, ok := shape.Intersect(float32(fx), float32(fy), float32(x), float32(y)); ok && d > 0 {
+						delta = d
+					}
+				}
+				far := -1
+				if ax, ay := fx+int(Cx[q]), fy+int(Cy[q]); inside(ax, ay) && !s.cells[ax+ay*s.xdim].Solid() {
+					far = ax + ay*s.xdim
+				}
+				s.links = append(s.links, wallLink{fluid: fluid, wall: wall, far: far, q: q, body: body, delta: delta})
+			}
+		}
+	}
+	s.linksValid = true
+}
+
+// Replace the half-way bounce-back on the links cut by an exact surface.
+// Runs after streaming, every link reads values no other link writes.
+func (s *Solver) interpolatedBounceBack() {
+	if !s.interpolate {
+		return
+	}
+	if !s.linksValid {
+		s.buildLinks()
+	}
+	pops := s.populations()
+	for _, l := range s.links {
+		if s.bodies[l.body].Shape == nil {
+			continue
+		}
+		opp := Opposite[l.q]
+		out := (*pops[opp])[l.wall] // population that left the fluid site towards the wall
+		var f float32
+		switch {
+		case l.far < 0:
+			f = out
+		case l.delta < 0.5:
+			f = 2*l.delta*out + (1-2*l.delta)*(*pops[opp])[l.fluid]
+		default:
+			f = (out + (2*l.delta-1)*(*pops[l.q])[l.far]) / (2 * l.delta)
+		}
+		if s.cells[l.wall] == MovingWallCell {
+			b := &s.bodies[l.body]
+			// wall velocity at the point the link crosses the surface
+			wx, wy := b.WallVelocity(float32(l.fluid%s.xdim)-l.delta*Cx[l.q], float32(l.fluid/s.xdim)-l.delta*Cy[l.q])
+			du := 6 * W[l.q] * s.rho[l.fluid] * (Cx[l.q]*wx + Cy[l.q]*wy)
+			if l.far >= 0 && l.delta >= 0.5 {
+				du /= 2 * l.delta
+			}
+			f += du
+		}
+		(*pops[l.q])[l.fluid] = f
+	}
+}
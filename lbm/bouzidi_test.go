@@ -0,0 +1,31 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterpolatedBounceBackBeatsStairSteps(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the flow past the cylinder takes thousands of steps to settle")
+	}
+	const want = 5.58
+	tests := []struct {
+		interp bool
+		tol    float64 // relative error allowed on the drag
+	}{
+		{true, 0.05},
+		{false, 0.12},
+	}
+	var errs [2]float64
+	for k, tt := range tests {
+		cd, _ := schaferTurek(t, tt.interp)
+		errs[k] = math.Abs(float64(cd)-want) / want
+		if errs[k] > tt.tol {
+			t.Errorf("interpolated %v: drag coefficient %g, want %g", tt.interp, cd, want)
+		}
+	}
+	if errs[0] >= errs[1] {
+		t.Errorf("interpolated bounce-back is off by %.1f%%, stair steps by %.1f%%", 100*errs[0], 100*errs[1])
+	}
+}
@@ -0,0 +1,98 @@
+package lbm
+
+import "fmt"
+
+// CellType classifies a lattice site
+type CellType uint8
+
+// Cell types. Interior sites are fluid unless painted otherwise, the edge
+// sites take the type of the boundary condition of their edge.
+const (
+	// Collided and streamed
+	FluidCell CellType = iota
+	// Solid site of a body, or no-slip edge, bounced back
+	WallCell
+	// Held at equilibrium at unit density and the flow velocity
+	InletCell
+	// Held at equilibrium at the outlet density and its own velocity
+	OutletCell
+	// Solid site whose walls move, bounced back with the wall momentum
+	MovingWallCell
+	// Free-slip edge, reflected specularly
+	SlipWallCell
+	// Fluid site inside a porous medium
+	PorousCell
+	// Fluid site that releases a transported quantity
+	SourceCell
+	// Stationary solid site held at the hot temperature
+	HotWallCell
+	// Stationary solid site held at the cold temperature
+	ColdWallCell
+	NumCellTypes
+)
+
+var cellNames = [NumCellTypes]string{"fluid", "wall", "inlet", "outlet", "moving", "slip", "porous", "source", "hot", "cold"}
+
+func (c CellType) String() string {
+	if c >= NumCellTypes {
+		return fmt.Sprintf("CellType(%d)", int(c))
+	}
+	return cellNames[c]
+}
+
+// Solid reports whether sites of this type hold no fluid
+func (c CellType) Solid() bool {
+	return c == WallCell || c == MovingWallCell || c == SlipWallCell || c == HotWallCell || c == ColdWallCell
+}
+
+// Cell type of the edge sites for each boundary condition
+var boundaryCells = [NumBoundaryTypes]CellType{InletCell, OutletCell, WallCell, SlipWallCell, FluidCell, InletCell, OutletCell, MovingWallCell, HotWallCell, ColdWallCell}
+
+// Cell returns the type of the site (x, y)
+func (s *Solver) Cell(x, y int) CellType {
+	return s.cells[x+y*s.xdim]
+}
+
+// SetCell sets the type of the site (x, y), sites on the edge of the grid
+// follow their boundary conditions and are left untouched. Solid sites
+// belong to body 0, which is created if there are no bodies yet, and take
+// its wall type so they move and heat with it.
+func (s *Solver) SetCell(x, y int, c CellType) {
+	if x < 1 || x > s.xdim-2 || y < 1 || y > s.ydim-2 {
+		return
+	}
+	i := x + y*s.xdim
+	if c.Solid() {
+		if len(s.bodies) == 0 {
+			s.AddBody(Body{CenterX: float32(x), CenterY: float32(y), Size: 1})
+		}
+		s.cellBody[i] = 0
+		c = s.bodyCell(0)
+	} else {
+		s.cellBody[i] = -1
+	}
+	s.cells[i] = c
+	s.linksValid = false
+}
+
+// Type the edge sites after the boundary conditions of their edges
+func (s *Solver) markEdges() {
+	if s.cells == nil {
+		return
+	}
+	mark := func(edge, x, y int) {
+		s.cells[x+y*s.xdim] = boundaryCells[s.boundaries[edge].typ]
+		s.cellBody[x+y*s.xdim] = -1
+	}
+	for x := 0; x < s.xdim; x++ {
+		mark(Bottom, x, 0)
+		mark(Top, x, s.ydim-1)
+	}
+	// the corners belong to the bottom and top edges unless they wrap around
+	y0, y1 := s.yRange()
+	for y := y0; y <= y1; y++ {
+		mark(Left, 0, y)
+		mark(Right, s.xdim-1, y)
+	}
+	s.linksValid = false
+}
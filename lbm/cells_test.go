@@ -0,0 +1,44 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPaintedCellsTakeTheWallTypeOfBody0(t *testing.T) {
+	tests := []struct {
+		name string
+		body Body
+		want CellType
+	}{
+		{"plain", Body{}, WallCell},
+		{"heated", Body{Heat: Heated}, HotWallCell},
+		{"cooled", Body{Heat: Cooled}, ColdWallCell},
+		{"moving", Body{Ux: 0.05}, MovingWallCell},
+	}
+	for _, tt := range tests {
+		s := CreateSolver(32, 16, 0.1, 0.03)
+		s.InitalizeLattice(32, 16, 0.1, 0.03, EMPTY)
+		s.AddBody(tt.body)
+		s.SetCell(10, 8, WallCell)
+		if got := s.Cell(10, 8); got != tt.want {
+			t.Errorf("%s body: painted cell is %v, want %v", tt.name, got, tt.want)
+		}
+		if got := s.BarrierBody(10, 8); got != 0 {
+			t.Errorf("%s body: painted cell belongs to body %d, want 0", tt.name, got)
+		}
+	}
+}
+
+func TestPaintedOutletHoldsTheOutletDensity(t *testing.T) {
+	for _, rho := range []float32{0.98, 1, 1.02} {
+		s := CreateSolver(32, 16, 0.1, 0.03)
+		s.SetBoundaryDensity(Right, rho)
+		s.InitalizeLattice(32, 16, 0.1, 0.03, EMPTY)
+		s.SetCell(10, 8, OutletCell)
+		s.Collide()
+		if got := s.Rho()[s.Index(10, 8)]; math.Abs(float64(got-rho)) > 1e-6 {
+			t.Errorf("outlet density %g: painted outlet holds %g", rho, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package lbm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Q is the number of discrete velocities of the D2Q9 lattice
+const Q = 9
+
+// Lattice directions. The populations of a site are passed to collision
+// operators in this order.
+const (
+	D0 = iota
+	DE
+	DN
+	DW
+	DS
+	DNE
+	DNW
+	DSW
+	DSE
+)
+
+// Lattice velocities and weights, indexed by direction
+var (
+	Cx = [Q]float32{0, 1, 0, -1, 0, 1, -1, -1, 1}
+	Cy = [Q]float32{0, 0, 1, 0, -1, 1, 1, -1, -1}
+	W  = [Q]float32{4.0 / 9.0, 1.0 / 9.0, 1.0 / 9.0, 1.0 / 9.0, 1.0 / 9.0, 1.0 / 36.0, 1.0 / 36.0, 1.0 / 36.0, 1.0 / 36.0}
+)
+
+// Opposite maps each direction to the one pointing the other way
+var Opposite = [Q]int{D0, DW, DS, DE, DN, DSW, DSE, DNE, DNW}
+
+// CollisionOperator relaxes the populations of a single lattice site.
+// Collide is called concurrently for different sites, so implementations
+// must not modify shared state.
+type CollisionOperator interface {
+	// Name returns a short human readable name, used in the UI
+	Name() string
+
+	// Collide relaxes the populations f in place. rho, ux and uy are the
+	// macroscopic moments of f and omega is the reciprocal of the
+	// relaxation time that sets the kinematic viscosity.
+	Collide(f *[Q]float32, rho, ux, uy, omega float32)
+}
+
+// Equilibrium computes the second order equilibrium populations for the
+// given density and velocity
+func Equilibrium(feq *[Q]float32, rho, ux, uy float32) {
+	u215 := 1.5 * (ux*ux + uy*uy)
+	for q := 0; q < Q; q++ {
+		cu := Cx[q]*ux + Cy[q]*uy
+		feq[q] = W[q] * rho * (1 + 3*cu + 4.5*cu*cu - u215)
+	}
+}
+
+// Moments computes the density and velocity of the populations f
+func Moments(f *[Q]float32) (rho, ux, uy float32) {
+	rho = f[D0] + f[DE] + f[DN] + f[DW] + f[DS] + f[DNE] + f[DNW] + f[DSW] + f[DSE]
+	invRho := 1.0 / rho
+	ux = (f[DE] + f[DNE] + f[DSE] - f[DW] - f[DNW] - f[DSW]) * invRho
+	uy = (f[DN] + f[DNE] + f[DNW] - f[DS] - f[DSE] - f[DSW]) * invRho
+	return
+}
+
+// Omega converts a kinematic viscosity in lattice units to the reciprocal
+// of the relaxation time
+func Omega(visc float32) float32 {
+	return 1.0 / (3*visc + 0.5)
+}
+
+// Viscosity converts the reciprocal of a relaxation time to a kinematic
+// viscosity in lattice units
+func Viscosity(omega float32) float32 {
+	return (1.0/omega - 0.5) / 3.0
+}
+
+// BGK is the single relaxation time Bhatnagar-Gross-Krook operator
+type BGK struct{}
+
+func (BGK) Name() string {
+	return "BGK"
+}
+
+func (BGK) Collide(f *[Q]float32, rho, ux, uy, omega float32) {
+	var feq [Q]float32
+	Equilibrium(&feq, rho, ux, uy)
+	for q := 0; q < Q; q++ {
+		f[q] += omega * (feq[q] - f[q])
+	}
+}
+
+var (
+	collisionMu        sync.RWMutex
+	collisionFactories = map[string]func() CollisionOperator{}
+)
+
+func init() {
+	RegisterCollisionOperator("bgk", func() CollisionOperator { return BGK{} })
+}
+
+// RegisterCollisionOperator makes a collision operator available by name
+// to NewCollisionOperator. Registering a name twice replaces the factory.
+func RegisterCollisionOperator(name string, factory func() CollisionOperator) {
+	collisionMu.Lock()
+	defer collisionMu.Unlock()
+	collisionFactories[strings.ToLower(name)] = factory
+}
+
+// NewCollisionOperator creates the collision operator registered as name
+func NewCollisionOperator(name string) (CollisionOperator, error) {
+	collisionMu.RLock()
+	defer collisionMu.RUnlock()
+	factory, ok := collisionFactories[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("lbm: unknown collision operator %q", name)
+	}
+	return factory(), nil
+}
+
+// CollisionOperatorNames returns the sorted names of all registered
+// collision operators
+func CollisionOperatorNames() []string {
+	collisionMu.RLock()
+	defer collisionMu.RUnlock()
+	names := make([]string, 0, len(collisionFactories))
+	for name := range collisionFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Decay rate of a shear wave ux = A sin(k y) in a fully periodic domain
+// gives the kinematic viscosity the operator really runs at
+func shearWaveViscosity(t *testing.T, op CollisionOperator, visc float32) float32 {
+	t.Helper()
+	const xdim, ydim = 4, 32
+	s := CreateSolver(xdim, ydim, 0, visc)
+	s.SetPeriodicBoundaries()
+	s.InitalizeLattice(xdim, ydim, 0, visc, EMPTY)
+	s.SetCollisionOperator(op)
+	k := 2 * math.Pi / ydim
+	for y := 0; y < ydim; y++ {
+		for x := 0; x < xdim; x++ {
+			s.SetEquilibrium(x, y, float32(0.01*math.Sin(k*float64(y))), 0, 1)
+		}
+	}
+	amplitude := func() float64 {
+		var a float64
+		for y := 0; y < ydim; y++ {
+			a += float64(s.ux[s.Index(0, y)]) * math.Sin(k*float64(y))
+		}
+		return a
+	}
+	const t0, t1 = 50, 550
+	var a0 float64
+	for step := 1; step <= t1; step++ {
+		s.Step()
+		if step == t0 {
+			a0 = amplitude()
+		}
+	}
+	return float32(math.Log(a0/amplitude()) / (k * k * (t1 - t0)))
+}
+
+func TestShearWaveViscosity(t *testing.T) {
+	for _, name := range CollisionOperatorNames() {
+		for _, visc := range []float32{0.01, 0.02, 0.1} {
+			op, err := NewCollisionOperator(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Viscosity(Omega(visc))
+			got := shearWaveViscosity(t, op, visc)
+			if math.Abs(float64(got-want)) > 0.03*float64(want) {
+				t.Errorf("%s at viscosity %g: shear wave decays at viscosity %g, want %g", name, visc, got, want)
+			}
+		}
+	}
+}
+
+func TestCollisionConservesMass(t *testing.T) {
+	for _, name := range CollisionOperatorNames() {
+		op, _ := NewCollisionOperator(name)
+		var f [Q]float32
+		Equilibrium(&f, 1.02, 0.08, -0.03)
+		f[DNE] += 0.004
+		f[DW] -= 0.002
+		rho, ux, uy := Moments(&f)
+		op.Collide(&f, rho, ux, uy, Omega(0.02))
+		r, vx, vy := Moments(&f)
+		if math.Abs(float64(r-rho)) > 1e-5 || math.Abs(float64(r*vx-rho*ux)) > 1e-5 || math.Abs(float64(r*vy-rho*uy)) > 1e-5 {
+			t.Errorf("%s: moments %g %g %g after collision, want %g %g %g", name, r, vx, vy, rho, ux, uy)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lbm
+
+// BodyForce is the load the fluid exerts on a body over one time step
+type BodyForce struct {
+	Fx, Fy float32
+
+	// Torque about the center of the body, counter-clockwise
+	Torque float32
+
+	// Drag and lift coefficients, normalized by the inflow velocity and the
+	// size of the body
+	Cd, Cl float32
+}
+
+// BodyForce returns the load on the body with the given ID over the last
+// time step
+func (s *Solver) BodyForce(id int) BodyForce {
+	if id >= len(s.forces) {
+		return BodyForce{}
+	}
+	f := s.forces[id]
+	b := &s.bodies[id]
+	if q := 0.5 * s.flowVel * s.flowVel * b.Size; q > 0 {
+		f.Cd = f.Fx / q
+		f.Cl = f.Fy / q
+	}
+	return f
+}
+
+// Sum the momentum exchanged across every link between a body and the
+// fluid, after the bounce-back. Moving walls use the Galilean invariant
+// form of Wen et al. (2014).
+func (s *Solver) computeForces() {
+	if !s.linksValid {
+		s.buildLinks()
+	}
+	if len(s.forces) != len(s.bodies) {
+		s.forces = make([]BodyForce, len(s.bodies))
+	}
+	for id := range s.forces {
+		s.forces[id] = BodyForce{}
+	}
+	pops := s.populations()
+	for _, l := range s.links {
+		b := &s.bodies[l.body]
+		opp := Opposite[l.q]
+		in := (*pops[opp])[l.wall]   // towards the wall
+		out := (*pops[l.q])[l.fluid] // back into the fluid
+		px, py := float32(l.fluid%s.xdim)-l.delta*Cx[l.q], float32(l.fluid/s.xdim)-l.delta*Cy[l.q]
+		fx, fy := Cx[opp]*(in+out), Cy[opp]*(in+out)
+		if s.cells[l.wall] == MovingWallCell {
+			wx, wy := b.WallVelocity(px, py)
+			fx -= wx * (in - out)
+			fy -= wy * (in - out)
+		}
+		f := &s.forces[l.body]
+		f.Fx += fx
+		f.Fy += fy
+		f.Torque += (px-b.CenterX)*fy - (py-b.CenterY)*fx
+	}
+	s.recordLift()
+}
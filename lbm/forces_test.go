@@ -0,0 +1,50 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Run the steady 2D-1 benchmark of Schäfer and Turek (1996), a cylinder of
+// diameter 10 just below the middle of a channel 4.1 diameters wide with a
+// parabolic inflow, at Re 20 to its steady state. Returns the drag and lift
+// coefficients normalized by the mass flux entering the channel.
+func schaferTurek(t *testing.T, interp bool) (cd, cl float32) {
+	t.Helper()
+	const d, h, umean = 10, 41, 0.05
+	const visc = umean * d / 20
+	s := CreateSolver(150, h+2, umean, visc)
+	s.SetBoundary(Left, ZouHeVelocity)
+	s.SetBoundary(Bottom, NoSlipWall)
+	s.SetBoundary(Top, NoSlipWall)
+	s.InitalizeLattice(150, h+2, umean, visc, EMPTY)
+	s.SetInterpolatedBounceBack(interp)
+	// the Zou-He inlet sits on the sites next to the left edge
+	s.AddShape(Circle{X: 2*d + 1, Y: 2*d + 0.5, R: d / 2})
+	for step := 0; step < 8000; step++ {
+		s.Step()
+		// impose the parabolic profile row by row on the Zou-He inlet
+		for y := 1; y <= h; y++ {
+			yy := float32(y) - 0.5
+			s.flowVel = 6 * umean * yy * (h - yy) / (h * h)
+			s.zouHeCell(Left, 1, y)
+		}
+		s.flowVel = umean
+	}
+	f := s.BodyForce(0)
+	scale := umean * h / s.MassFlux(Left)
+	return f.Cd * scale, f.Cl * scale
+}
+
+func TestCylinderDragSchaferTurek(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the flow past the cylinder takes thousands of steps to settle")
+	}
+	cd, cl := schaferTurek(t, true)
+	if math.Abs(float64(cd-5.58)) > 0.05*5.58 {
+		t.Errorf("drag coefficient %g, want 5.58", cd)
+	}
+	if math.Abs(float64(cl-0.0106)) > 0.005 {
+		t.Errorf("lift coefficient %g, want 0.0106", cl)
+	}
+}
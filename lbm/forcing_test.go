@@ -0,0 +1,26 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGuoForcingPoiseuille(t *testing.T) {
+	tests := []struct {
+		visc, fx float32
+	}{
+		{0.05, 1e-6},
+		{0.1, 1e-5},
+		{1.0 / 6, 1e-5},
+		{0.1, -2e-5},
+	}
+	for _, tt := range tests {
+		got, want := poiseuille(t, BGK{}, tt.visc, tt.fx, 8000)
+		peak := want[len(want)/2]
+		for i := range got {
+			if math.Abs(float64(got[i]-want[i])) > 0.02*math.Abs(float64(peak)) {
+				t.Errorf("viscosity %g, force %g: ux %g at row %d, want %g", tt.visc, tt.fx, got[i], i+1, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Fill an n by n periodic box moving at vel with the second fluid and put a
+// circular drop of the first fluid of radius r in the middle
+func immiscibleDrop(n int, vel, visc, tension float32, r float64) *Solver {
+	s := CreateSolver(n, n, vel, visc)
+	s.SetPeriodicBoundaries()
+	s.InitalizeLattice(n, n, vel, visc, EMPTY)
+	s.EnableImmiscible(visc, tension)
+	c := float64(n)/2 - 0.5
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			d := math.Hypot(float64(x)-c, float64(y)-c)
+			s.SetPhase(x, y, float32(-math.Tanh(d-r)))
+		}
+	}
+	return s
+}
+
+// Masses of the two fluids
+func fluidMasses(s *Solver) (first, second float64) {
+	var f [Q]float32
+	for i := 0; i < s.numElements; i++ {
+		s.gather(i, &f)
+		for q := 0; q < Q; q++ {
+			first += float64(s.immiscible.r[q][i])
+			second += float64(f[q] - s.immiscible.r[q][i])
+		}
+	}
+	return first, second
+}
+
+func TestImmiscibleDropLaplaceLaw(t *testing.T) {
+	if testing.Short() {
+		t.Skip("drops take thousands of steps to come to rest")
+	}
+	const n, visc = 64, 1.0 / 6
+	for _, tension := range []float32{0.005, 0.01} {
+		for _, r := range []float64{8, 12} {
+			s := immiscibleDrop(n, 0, visc, tension, r)
+			for step := 0; step < 4000; step++ {
+				s.Step()
+			}
+			// radius of a circle with the area of the first fluid
+			var area float64
+			for _, phase := range s.Phase() {
+				area += float64(1+phase) / 2
+			}
+			radius := math.Sqrt(area / math.Pi)
+			// the pressure is rho / 3, and the jump across a circular
+			// interface sigma / R in two dimensions
+			got := float64(s.rho[s.Index(n/2, n/2)]-s.rho[s.Index(0, 0)]) / 3
+			want := float64(tension) / radius
+			if math.Abs(got-want) > 0.08*want {
+				t.Errorf("tension %g, radius %.2f: pressure jump %g, want %g", tension, radius, got, want)
+			}
+		}
+	}
+}
+
+func TestImmiscibleFluidsKeepTheirMass(t *testing.T) {
+	for _, vel := range []float32{0, 0.05} {
+		s := immiscibleDrop(48, vel, 0.1, 0.005, 10)
+		first0, second0 := fluidMasses(s)
+		for step := 0; step < 1000; step++ {
+			s.Step()
+		}
+		first, second := fluidMasses(s)
+		if math.Abs(first-first0) > 1e-4*first0 {
+			t.Errorf("velocity %g: first fluid mass %g, want %g", vel, first, first0)
+		}
+		if math.Abs(second-second0) > 1e-4*second0 {
+			t.Errorf("velocity %g: second fluid mass %g, want %g", vel, second, second0)
+		}
+	}
+}
+
+func TestImmiscibleLayersShearWithViscosityRatio(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the layers take thousands of steps to reach their steady shear")
+	}
+	const xdim, ydim, lid, visc = 4, 42, 0.02, 0.1
+	const h = (ydim - 2) / 2
+	for _, ratio := range []float32{0.25, 4} {
+		s := CreateSolver(xdim, ydim, lid, visc)
+		s.SetBoundary(Left, Periodic)
+		s.SetBoundary(Right, Periodic)
+		s.SetBoundary(Bottom, NoSlipWall)
+		s.SetBoundary(Top, MovingWall)
+		s.SetBoundaryVelocity(Top, lid)
+		s.InitalizeLattice(xdim, ydim, lid, visc, EMPTY)
+		s.EnableImmiscible(ratio*visc, 0.005)
+		// the first fluid in the bottom half and the second in the top half,
+		// sheared uniformly as if they were one
+		for y := 0; y < ydim; y++ {
+			for x := 0; x < xdim; x++ {
+				s.SetEquilibrium(x, y, lid*(float32(y)-0.5)/(2*h), 0, 1)
+				s.SetPhase(x, y, float32(-math.Tanh(float64(y)-h-0.5)))
+			}
+		}
+		for step := 0; step < 16000; step++ {
+			s.Step()
+		}
+		// both layers carry the same shear stress, so the velocity slopes
+		// away from the interface are in the inverse ratio of the
+		// viscosities
+		slope := func(y0, y1 int) float32 {
+			return (s.ux[s.Index(1, y1)] - s.ux[s.Index(1, y0)]) / float32(y1-y0)
+		}
+		got := slope(4, 16) / slope(25, 37)
+		if math.Abs(float64(got-ratio)) > 0.02*float64(ratio) {
+			t.Errorf("viscosity ratio %g: slope ratio %g", ratio, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package lbm
+
+// KBC is the entropic multi-relaxation operator of Karlin, Bösch and
+// Chikatamarla. The departure from equilibrium is split into a shear part,
+// which carries the deviatoric stress and relaxes with omega, and a higher
+// order part whose relaxation is chosen at every site to maximise the
+// entropy. This keeps the lattice stable at viscosities where BGK blows up,
+// so vortex streets survive at much higher Reynolds numbers.
+type KBC struct{}
+
+func (KBC) Name() string {
+	return "KBC"
+}
+
+func (KBC) Collide(f *[Q]float32, rho, ux, uy, omega float32) {
+	var feq, df [Q]float32
+	Equilibrium(&feq, rho, ux, uy)
+
+	// Normal stress difference and shear stress of the departure from equilibrium
+	var nxx, pxy float32
+	for q := 0; q < Q; q++ {
+		df[q] = f[q] - feq[q]
+		nxx += (Cx[q]*Cx[q] - Cy[q]*Cy[q]) * df[q]
+		pxy += Cx[q] * Cy[q] * df[q]
+	}
+
+	// Split the departure into its shear part ds and the remainder dh
+	var ds, dh [Q]float32
+	for q := 0; q < Q; q++ {
+		ds[q] = 0.25 * ((Cx[q]*Cx[q]-Cy[q]*Cy[q])*nxx + Cx[q]*Cy[q]*pxy)
+		dh[q] = df[q] - ds[q]
+	}
+
+	// Entropic stabilizer, falls back to BGK when the higher order part vanishes
+	beta := 0.5 * omega
+	gamma := float32(2)
+	var sh, hh float32
+	for q := 0; q < Q; q++ {
+		sh += ds[q] * dh[q] / feq[q]
+		hh += dh[q] * dh[q] / feq[q]
+	}
+	if hh > 1e-12 {
+		gamma = 1/beta - (2-1/beta)*sh/hh
+	}
+
+	for q := 0; q < Q; q++ {
+		f[q] -= beta * (2*ds[q] + gamma*dh[q])
+	}
+}
+
+func init() {
+	RegisterCollisionOperator("kbc", func() CollisionOperator { return KBC{} })
+}
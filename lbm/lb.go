@@ -0,0 +1,1053 @@
+// Package lbm implements a headless D2Q9 lattice-Boltzmann fluid solver.
+// It has no dependency on the UI or on gomobile, so it can be driven from
+// the app, from command line tools or from tests.
+package lbm
+
+// LB Code Copyright
+// ~~~~~~~~~~~~~~~~~
+// A lattice-Boltzmann fluid simulation in JavaScript, using HTML5 canvas for graphics
+
+// Copyright 2013, Daniel V. Schroeder
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated data and documentation (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies
+// of the Software, and to permit persons to whom the Software is furnished to do
+// so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Except as contained in this notice, the name of the author shall not be used in
+// advertising or otherwise to promote the sale, use or other dealings in this
+// Software without prior written authorization.
+
+// Credits:
+// The "wind tunnel" entry/exit conditions are inspired by Graham Pullan's code
+// (http://www.many-core.group.cam.ac.uk/projects/LBdemo.shtml).  Additional inspiration from
+// Thomas Pohl's applet (http://thomas-pohl.info/work/lba.html).  Other portions of code are based
+// on Wagner (http://www.ndsu.edu/physics/people/faculty/wagner/lattice_boltzmann_codes/) and
+// Gonsalves (http://www.physics.buffalo.edu/phy411-506-2004/index.html; code adapted from Succi,
+// http://global.oup.com/academic/product/the-lattice-boltzmann-equation-9780199679249).
+
+// Revision history:
+// First version, with only start/stop, speed, and viscosity controls, February 2013
+// Added resolution control, mouse interaction, plot options, etc., March 2013
+// Added sensor, contrast slider, improved tracer placement, Fy period readout, May 2013
+// Added option to animate using setTimeout instead of requestAnimationFrame, July 2013
+// Added "Flowline" plotting (actually just line segments), August 2013
+
+// Still to do:
+// * Fix the apparent bug in the force calculation that gives inconsistent results depending
+// 	on initial conditions.  Perhaps bounce-backs between adjacent barrier sites don't cancel?
+// * Grabbing the sensor while "drag fluid" selected causes a momentary drag at previous mouse location.
+// * Try to pass two-fingered touch events on to the browser, so it's still possible to zoom in and out.
+// * Work on GUI control layout, especially for smaller screens.
+// * Treat ends symmetrically when flow speed is zero.
+// * Try some other visualization techniques.
+
+// Ported to Golang by Chandan Prasad.
+// For use on phones using gomobile (experimental)
+
+import (
+	"fmt"
+	"image"
+	image_color "image/color"
+	"math"
+)
+
+// Constant definitions of barrier types
+const (
+	LINE    = 0
+	CIRCLE  = 1
+	CAVITY  = 2 // no barrier, lid-driven cavity
+	BENARD  = 3 // no barrier, Rayleigh-Bénard cell heated from below
+	HEATED  = 4 // heated cylinder in a cold enclosure
+	EMPTY   = 5 // no barrier
+	DROP    = 6 // no barrier, liquid drop on a wall for the Shan-Chen model
+	PHASES  = 7 // no barrier, liquid and vapour separating for the Shan-Chen model
+	FINGERS = 8 // no barrier, one fluid pushing another along a channel
+	POROUS  = 9 // porous block
+)
+
+// Flow properties that can be plotted with PlotToImage
+const (
+	PlotRho = iota
+	PlotUx
+	PlotUy
+	PlotSpeed
+	PlotCurl
+	PlotViscosity
+	PlotScalar
+	PlotTemperature
+	PlotPhase
+	NumPlotTypes
+)
+
+// Solver stores the LBM solver params
+type Solver struct {
+	// Grid Dimensions
+	xdim        int
+	ydim        int
+	numElements int
+
+	// Initial Conditions
+	flowVel  float32
+	flowVisc float32
+
+	// microscopic densities along each lattice direction
+	n0  []float32
+	nN  []float32
+	nS  []float32
+	nE  []float32
+	nW  []float32
+	nNE []float32
+	nSE []float32
+	nNW []float32
+	nSW []float32
+
+	// spare population slices that streaming swaps with, indexed by direction
+	spare [Q][]float32
+
+	// macroscopic density
+	rho []float32
+
+	// macroscopic velocity
+	ux []float32
+	uy []float32
+
+	curl []float32
+
+	// effective kinematic viscosity, including the subgrid eddy viscosity
+	visc []float32
+
+	// Smagorinsky constant, 0 disables the subgrid model
+	smagorinsky float32
+
+	// Boundary conditions of the domain edges
+	boundaries [NumEdges]edgeBoundary
+
+	running       bool
+	time          int
+	stepsPerFrame int
+
+	// Barrier
+	barrierType int
+	cells       []CellType // type of every site
+	cellBody    []int      // ID of the body each solid site belongs to, -1 for none
+
+	// Solid fraction of every porous site, and of the block of the POROUS
+	// barrier
+	solidFraction  []float32
+	porousFraction float32
+
+	// Rigid bodies the barriers belong to, and the load on each of them
+	bodies []Body
+	forces []BodyForce
+
+	// Time series of the lift on every body, in a ring buffer
+	lift    [][]float32
+	liftPos int
+	liftLen int
+
+	// Point probes
+	probes []*probe
+
+	// Tracer particles
+	tracers       []Tracer
+	tracerMode    int
+	tracerSpacing int
+	tracerStokes  float32
+	tracerRelease int // steps until the next inlet column is released
+
+	// Passive scalar, nil when disabled
+	scalar *adField
+
+	// Uniform external force density and the field added to it, nil when
+	// the force is uniform
+	forceX, forceY           float32
+	forceFieldX, forceFieldY []float32
+
+	// Shan-Chen interaction strength, wettability of the barriers, coexisting
+	// vapour and liquid densities and pseudopotential of every site
+	shanChen     bool
+	scStrength   float32
+	contactAngle float32
+	scWetting    float32
+	scVapour     float32
+	scLiquid     float32
+	psi          []float32
+
+	// Populations and phase field of the first of two immiscible fluids,
+	// nil for a single fluid
+	immiscible *colorField
+
+	// Temperature, nil when disabled, and the Boussinesq buoyancy per unit
+	// temperature derived from the Rayleigh and Prandtl numbers
+	thermal  *adField
+	rayleigh float32
+	prandtl  float32
+	buoyancy float32
+
+	// Streamline overlay
+	streamlineSpacing int
+	streamlineColor   image_color.RGBA
+
+	// Interpolated bounce-back on the surface of bodies with an exact shape
+	interpolate bool
+	links       []wallLink
+	linksValid  bool
+
+	// Collision operator applied to every site
+	collision CollisionOperator
+
+	// Colors
+	nColors   int
+	redList   []int
+	greenList []int
+	blueList  []int
+}
+
+func CreateSolver(xdim, ydim int, fVel, fVisc float32) *Solver {
+	solver := new(Solver)
+	solver.InitSolver(xdim, ydim, fVel, fVisc)
+	solver.SetDefaultBoundaries()
+	solver.interpolate = true
+	solver.contactAngle = 90
+	solver.porousFraction = 0.05
+	return solver
+}
+
+func (s *Solver) SetFlowVelocity(vel float32) {
+	s.flowVel = vel
+}
+
+func (s *Solver) FlowVelocity() float32 {
+	return s.flowVel
+}
+
+func (s *Solver) SetFlowViscosity(visc float32) {
+	s.flowVisc = visc
+}
+
+func (s *Solver) FlowViscosity() float32 {
+	return s.flowVisc
+}
+
+// SetCollisionOperator selects the collision operator, nil restores BGK
+func (s *Solver) SetCollisionOperator(op CollisionOperator) {
+	if op == nil {
+		op = BGK{}
+	}
+	s.collision = op
+}
+
+// CollisionOperator returns the collision operator in use
+func (s *Solver) CollisionOperator() CollisionOperator {
+	return s.collision
+}
+
+func (s *Solver) SetStepsPerFrame(steps int) {
+	s.stepsPerFrame = steps
+}
+
+func (s *Solver) StepsPerFrame() int {
+	return s.stepsPerFrame
+}
+
+// Xdim returns the number of lattice sites along x
+func (s *Solver) Xdim() int {
+	return s.xdim
+}
+
+// Ydim returns the number of lattice sites along y
+func (s *Solver) Ydim() int {
+	return s.ydim
+}
+
+// Time returns the number of time steps taken since the lattice was initialized
+func (s *Solver) Time() int {
+	return s.time
+}
+
+// Index returns the index into the field slices for the site (x, y)
+func (s *Solver) Index(x, y int) int {
+	return x + y*s.xdim
+}
+
+// Rho returns the macroscopic density field, indexed with Index
+func (s *Solver) Rho() []float32 {
+	return s.rho
+}
+
+// Ux returns the x component of the macroscopic velocity field
+func (s *Solver) Ux() []float32 {
+	return s.ux
+}
+
+// Uy returns the y component of the macroscopic velocity field
+func (s *Solver) Uy() []float32 {
+	return s.uy
+}
+
+// Curl returns the curl of the macroscopic velocity field, it is
+// recomputed on every call
+func (s *Solver) Curl() []float32 {
+	s.ComputeCurl()
+	return s.curl
+}
+
+// EffectiveViscosity returns the kinematic viscosity used at every site in
+// the last collision, including the Smagorinsky eddy viscosity
+func (s *Solver) EffectiveViscosity() []float32 {
+	return s.visc
+}
+
+// SetSmagorinskyConstant enables the Smagorinsky subgrid model with the
+// constant cs, typically between 0.1 and 0.2. A value of 0 disables it.
+func (s *Solver) SetSmagorinskyConstant(cs float32) {
+	s.smagorinsky = cs
+}
+
+func (s *Solver) SmagorinskyConstant() float32 {
+	return s.smagorinsky
+}
+
+// Barrier reports whether the site (x, y) is a solid site of a body
+func (s *Solver) Barrier(x, y int) bool {
+	return s.cellBody[x+y*s.xdim] >= 0
+}
+
+// SetBarrier turns the site (x, y) into a wall of body 0 or back into
+// fluid, sites on the edge of the grid are left untouched
+func (s *Solver) SetBarrier(x, y int, b bool) {
+	if b {
+		s.SetCell(x, y, WallCell)
+	} else {
+		s.SetCell(x, y, FluidCell)
+	}
+}
+
+// BarrierSize returns the extent of the barriers across the flow, in
+// lattice units
+func (s *Solver) BarrierSize() int {
+	ymin, ymax := s.ydim, -1
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			if s.cellBody[x+y*s.xdim] >= 0 {
+				if y < ymin {
+					ymin = y
+				}
+				if y > ymax {
+					ymax = y
+				}
+			}
+		}
+	}
+	if ymax < ymin {
+		return 0
+	}
+	return ymax - ymin + 1
+}
+
+// ReynoldsNumber returns the Reynolds number the simulation is running at,
+// based on the inflow velocity, the barrier size and the viscosity. For a
+// lid-driven cavity the lid velocity and the cavity width are used, for
+// the fingering channel its width and for a porous block its height.
+// Natural convection and the multiphase barriers have no imposed velocity
+// and report 0.
+func (s *Solver) ReynoldsNumber() float32 {
+	switch s.barrierType {
+	case CAVITY:
+		return s.boundaries[Top].velocity * float32(s.xdim-2) / s.flowVisc
+	case BENARD, HEATED, DROP, PHASES:
+		return 0
+	case FINGERS:
+		return s.flowVel * float32(s.ydim-2) / s.flowVisc
+	case POROUS:
+		_, _, ymin, ymax, _ := s.porousExtent()
+		return s.flowVel * float32(ymax-ymin+1) / s.flowVisc
+	}
+	return s.flowVel * float32(s.BarrierSize()) / s.flowVisc
+}
+
+func (s *Solver) InitSolver(xmax, ymax int, fVel, fVisc float32) {
+	// Create the arrays of fluid particle densities, etc. (using 1D arrays for speed):
+	// To index into these arrays, use x + y*xdim, traversing rows first and then columns.
+
+	// Grid
+	s.xdim = xmax
+	s.ydim = ymax
+	s.numElements = xmax * ymax
+
+	s.flowVel = fVel
+	s.flowVisc = fVisc
+
+	s.nColors = 256
+
+	if s.collision == nil {
+		s.collision = BGK{}
+	}
+
+	s.time = 0
+	s.running = false
+	s.stepsPerFrame = 3
+
+	s.cells = make([]CellType, s.numElements)
+	s.cellBody = make([]int, s.numElements)
+	s.solidFraction = make([]float32, s.numElements)
+	for i := range s.cellBody {
+		s.cellBody[i] = -1
+	}
+	s.markEdges()
+	s.bodies = nil
+	s.forces = nil
+	s.lift = nil
+	s.linksValid = false
+	s.resetProbes()
+	s.tracers = nil
+
+	s.n0 = make([]float32, s.numElements) // microscopic densities along each lattice direction
+	s.nN = make([]float32, s.numElements)
+	s.nS = make([]float32, s.numElements)
+	s.nE = make([]float32, s.numElements)
+	s.nW = make([]float32, s.numElements)
+	s.nNE = make([]float32, s.numElements)
+	s.nSE = make([]float32, s.numElements)
+	s.nNW = make([]float32, s.numElements)
+	s.nSW = make([]float32, s.numElements)
+	for q := 1; q < Q; q++ {
+		s.spare[q] = make([]float32, s.numElements)
+	}
+
+	s.rho = make([]float32, s.numElements) // macroscopic density
+	s.ux = make([]float32, s.numElements)  // macroscopic velocity
+	s.uy = make([]float32, s.numElements)
+	s.curl = make([]float32, s.numElements)
+	s.visc = make([]float32, s.numElements)
+
+	// the force field and the transported fields follow the new grid
+	if len(s.forceFieldX) != s.numElements {
+		s.forceFieldX, s.forceFieldY = nil, nil
+	}
+	if s.scalar != nil {
+		s.EnableScalar(s.scalar.diffusivity)
+	}
+	if s.thermal != nil {
+		s.EnableThermal(s.rayleigh, s.prandtl)
+	}
+	if s.immiscible != nil {
+		s.immiscible = newColorField(s.numElements, s.immiscible.visc2, s.immiscible.tension)
+	}
+}
+
+func (s *Solver) InitalizeLattice(xmax, ymax int, fVel, fVisc float32, barrierType int) {
+
+	s.InitSolver(xmax, ymax, fVel, fVisc)
+
+	// Initialize to a steady rightward flow with no barriers:
+	s.ClearBarriers()
+
+	// Create a simple barrier
+	s.barrierType = barrierType
+	s.CreateBarrier(barrierType)
+	switch barrierType {
+	case CAVITY:
+		s.SetCavityBoundaries()
+	case BENARD:
+		s.SetBenardBoundaries()
+	case HEATED:
+		s.SetEnclosureBoundaries()
+	case DROP:
+		s.SetChannelBoundaries()
+	case PHASES:
+		s.SetPeriodicBoundaries()
+	case FINGERS:
+		s.SetFingeringBoundaries()
+	}
+
+	// Create Color Map
+	s.CreateColorMap()
+
+	// Initalize fluid
+	s.InitFluid()
+
+	// Seed the tracers again around the new barriers
+	s.SeedTracers(s.tracerMode, s.tracerSpacing)
+}
+
+// Set all densities in a cell to their equilibrium values for a given velocity and density:
+// (If density is omitted, it's left unchanged.)
+func (s *Solver) SetEquilibrium(x, y int, newux, newuy, newrho float32) {
+	i := x + (y * s.xdim)
+
+	// Special case for dragging fluid
+	if newrho == -1.0 {
+		newrho = s.rho[i]
+	}
+
+	var f [Q]float32
+	Equilibrium(&f, newrho, newux, newuy)
+	s.scatter(i, &f)
+	s.rho[i] = newrho
+	s.ux[i] = newux
+	s.uy[i] = newuy
+}
+
+// Function to initialize or re-initialize the fluid, based on speed slider setting:
+// (The fluid in a lid-driven cavity, a natural convection or a multiphase setup starts at rest.)
+func (s *Solver) InitFluid() {
+	u0 := float32(s.flowVel)
+	switch s.barrierType {
+	case CAVITY, BENARD, HEATED, DROP, PHASES:
+		u0 = 0
+	}
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			s.SetEquilibrium(x, y, u0, 0, 1)
+			s.curl[x+y*s.xdim] = 0.0
+			s.visc[x+y*s.xdim] = s.flowVisc
+		}
+	}
+	if s.barrierType == DROP || s.barrierType == PHASES {
+		s.initMultiphase()
+	}
+	if s.immiscible != nil {
+		s.resetPhase()
+	}
+	s.liftPos, s.liftLen = 0, 0
+	if s.scalar != nil {
+		s.resetADField(s.scalar, 0)
+	}
+	if s.thermal != nil {
+		s.updateThermal()
+		s.resetTemperature()
+	}
+}
+
+// gather copies the populations of site i into f
+func (s *Solver) gather(i int, f *[Q]float32) {
+	f[D0] = s.n0[i]
+	f[DE] = s.nE[i]
+	f[DN] = s.nN[i]
+	f[DW] = s.nW[i]
+	f[DS] = s.nS[i]
+	f[DNE] = s.nNE[i]
+	f[DNW] = s.nNW[i]
+	f[DSW] = s.nSW[i]
+	f[DSE] = s.nSE[i]
+}
+
+// scatter copies f back into the populations of site i
+func (s *Solver) scatter(i int, f *[Q]float32) {
+	s.n0[i] = f[D0]
+	s.nE[i] = f[DE]
+	s.nN[i] = f[DN]
+	s.nW[i] = f[DW]
+	s.nS[i] = f[DS]
+	s.nNE[i] = f[DNE]
+	s.nNW[i] = f[DNW]
+	s.nSW[i] = f[DSW]
+	s.nSE[i] = f[DSE]
+}
+
+// Collide the interior sites of row y with the selected collision operator
+func (s *Solver) collideRow(y int, omega float32) {
+	var f, src [Q]float32
+	x0, x1 := s.xRange()
+	for x := x0; x <= x1; x++ {
+		i := x + y*s.xdim // array index for this lattice site
+		switch s.cells[i] {
+		case WallCell, MovingWallCell, SlipWallCell, HotWallCell, ColdWallCell:
+			// solid sites hold no fluid
+			continue
+		case InletCell:
+			s.SetEquilibrium(x, y, s.flowVel, 0, 1)
+			continue
+		case OutletCell:
+			s.gather(i, &f)
+			_, thisux, thisuy := Moments(&f)
+			s.SetEquilibrium(x, y, thisux, thisuy, s.outletDensity())
+			continue
+		}
+		s.gather(i, &f)
+		pre := f
+		thisrho, thisux, thisuy := Moments(&f)
+		fx, fy := s.siteForce(i, thisrho)
+		forced := fx != 0 || fy != 0
+		if forced {
+			// the velocity of the site includes half the force
+			thisux += 0.5 * fx / thisrho
+			thisuy += 0.5 * fy / thisrho
+			guoHalfSource(&src, thisux, thisuy, fx, fy)
+			for q := 0; q < Q; q++ {
+				f[q] += src[q]
+			}
+		}
+		s.rho[i] = thisrho
+		s.ux[i] = thisux
+		s.uy[i] = thisuy
+		cellOmega := omega
+		if s.immiscible != nil {
+			cellOmega = s.immiscibleOmega(i)
+		}
+		if s.smagorinsky > 0 {
+			cellOmega = SmagorinskyOmega(&f, thisrho, thisux, thisuy, cellOmega, s.smagorinsky)
+		}
+		s.visc[i] = Viscosity(cellOmega)
+		s.collision.Collide(&f, thisrho, thisux, thisuy, cellOmega)
+		if forced {
+			for q := 0; q < Q; q++ {
+				f[q] += src[q]
+			}
+		}
+		if s.cells[i] == PorousCell {
+			partialBounceBack(&f, &pre, s.solidFraction[i])
+		}
+		if s.immiscible != nil {
+			s.separate(i, &f, cellOmega)
+		}
+		s.scatter(i, &f)
+	}
+}
+
+// Collide particles within each cell (here's the physics!):
+func (s *Solver) Collide() {
+	// reciprocal of relaxation time
+	omega := Omega(s.flowVisc)
+	if s.shanChen {
+		s.updatePsi()
+	}
+	if s.immiscible != nil {
+		s.updatePhase()
+	}
+
+	y0, y1 := s.yRange()
+	for y := y0; y <= y1; y++ {
+		s.collideRow(y, omega)
+	}
+}
+
+type empty2 struct{}
+
+// Collide particles within each cell (here's the physics!):
+func (s *Solver) CollideThreaded() {
+	// reciprocal of relaxation time
+	omega := Omega(s.flowVisc)
+	if s.shanChen {
+		s.updatePsi()
+	}
+	if s.immiscible != nil {
+		s.updatePhase()
+	}
+
+	y0, y1 := s.yRange()
+	sem := make(chan empty2, y1-y0+1)
+
+	for yy := y0; yy <= y1; yy++ {
+		go func(y int) {
+			s.collideRow(y, omega)
+			sem <- empty2{}
+		}(yy)
+	}
+
+	for y := y0; y <= y1; y++ {
+		<-sem
+	}
+}
+
+// Pointers to the population slices, indexed by direction
+func (s *Solver) populations() [Q]*[]float32 {
+	return [Q]*[]float32{&s.n0, &s.nE, &s.nN, &s.nW, &s.nS, &s.nNE, &s.nNW, &s.nSW, &s.nSE}
+}
+
+// Move the particles moving in direction q to the next site. Every site is
+// pulled from its upstream neighbour into a spare slice, which then replaces
+// the populations. Edge sites of a periodic axis pull across the opposite
+// edge, other edge sites keep the values set by SetBoundaries.
+func (s *Solver) streamDirection(q int) {
+	pops := s.populations()
+	src := *pops[q]
+	dst := s.spare[q]
+	copy(dst, src)
+
+	cx, cy := int(Cx[q]), int(Cy[q])
+	x0, x1 := s.xRange()
+	y0, y1 := s.yRange()
+	// columns that pull from inside the grid, the rest wrap around
+	bx0, bx1 := x0, x1
+	if bx0-cx < 0 {
+		bx0 = cx
+	}
+	if bx1-cx > s.xdim-1 {
+		bx1 = s.xdim - 1 + cx
+	}
+	for y := y0; y <= y1; y++ {
+		sy := y - cy
+		if sy < 0 {
+			sy += s.ydim
+		} else if sy >= s.ydim {
+			sy -= s.ydim
+		}
+		row, srcRow := y*s.xdim, sy*s.xdim
+		copy(dst[row+bx0:row+bx1+1], src[srcRow+bx0-cx:srcRow+bx1-cx+1])
+		if bx0 > x0 {
+			dst[row+x0] = src[srcRow+s.xdim-1]
+		}
+		if bx1 < x1 {
+			dst[row+x1] = src[srcRow]
+		}
+	}
+
+	*pops[q] = dst
+	s.spare[q] = src
+}
+
+// Handle bounce-back from the barriers in row y, moving walls add their
+// momentum to the bounced populations
+func (s *Solver) bounceBackRow(y int) {
+	for x := 1; x < s.xdim-1; x++ {
+		if s.cells[x+y*s.xdim].Solid() {
+			var index = x + y*s.xdim
+			s.nE[x+1+y*s.xdim] = s.nW[index]
+			s.nW[x-1+y*s.xdim] = s.nE[index]
+			s.nN[x+(y+1)*s.xdim] = s.nS[index]
+			s.nS[x+(y-1)*s.xdim] = s.nN[index]
+			s.nNE[x+1+(y+1)*s.xdim] = s.nSW[index]
+			s.nNW[x-1+(y+1)*s.xdim] = s.nSE[index]
+			s.nSE[x+1+(y-1)*s.xdim] = s.nNW[index]
+			s.nSW[x-1+(y-1)*s.xdim] = s.nNE[index]
+			if s.cells[index] == MovingWallCell {
+				s.moveWalls(x, y, &s.bodies[s.cellBody[index]])
+			}
+		}
+	}
+}
+
+// Move particles along their directions of motion:
+func (s *Solver) Stream() {
+	for q := 1; q < Q; q++ {
+		s.streamDirection(q)
+	}
+	for y := 1; y < s.ydim-1; y++ { // Now handle bounce-back from barriers
+		s.bounceBackRow(y)
+	}
+	s.interpolatedBounceBack()
+	s.computeForces()
+}
+
+type streamSem struct{}
+
+// Move particles along their directions of motion:
+func (s *Solver) StreamThreaded() {
+	sem1 := make(chan streamSem, Q-1)
+
+	for qq := 1; qq < Q; qq++ {
+		go func(q int) {
+			s.streamDirection(q)
+			sem1 <- streamSem{}
+		}(qq)
+	}
+
+	// Synchronize all threads
+	for q := 1; q < Q; q++ {
+		<-sem1
+	}
+
+	sem2 := make(chan streamSem, s.ydim-1)
+	for yy := 1; yy < s.ydim-1; yy++ { // Now handle bounce-back from barriers
+		go func(y int) {
+			s.bounceBackRow(y)
+			sem2 <- streamSem{}
+		}(yy)
+	}
+
+	// Sync
+	for yy := 1; yy < s.ydim-1; yy++ {
+		<-sem2
+	}
+	s.interpolatedBounceBack()
+	s.computeForces()
+
+}
+
+// DragFluidProperties describes a push applied to the fluid by the user,
+// in grid coordinates and lattice velocity units
+type DragFluidProperties struct {
+	PushX  int
+	PushY  int
+	PushUX float32
+	PushUY float32
+}
+
+// CheckFlowStability re-initializes the fluid if the density has gone
+// non-positive, returns false if the simulation had to be reset
+func (s *Solver) CheckFlowStability() bool {
+	stable := true
+	for x := 0; x < s.xdim; x++ {
+		// look at middle row only
+		index := x + (s.ydim/2)*s.xdim
+		if !s.cells[index].Solid() && s.rho[index] <= 0 {
+			stable = false
+		}
+	}
+
+	if !stable {
+		fmt.Println("Simulation has become unstable due to excessive fluid speeds.")
+		s.InitFluid()
+	}
+
+	return stable
+}
+
+// "Drag" the fluid in a direction determined by the mouse (or touch) motion:
+// (The drag affects a "circle", 5 px in diameter, centered on the given coordinates.)
+func (s *Solver) DragFluid(pushX, pushY int, pushUX, pushUY float32) {
+	// First make sure we're not too close to edge:
+	margin := 3
+	if (pushX > margin) && (pushX < s.xdim-1-margin) && (pushY > margin) && (pushY < s.ydim-1-margin) {
+		for dx := -1; dx <= 1; dx++ {
+			s.SetEquilibrium(pushX+dx, pushY+2, pushUX, pushUY, -1.0)
+			s.SetEquilibrium(pushX+dx, pushY-2, pushUX, pushUY, -1.0)
+		}
+		for dx := -2; dx <= 2; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				s.SetEquilibrium(pushX+dx, pushY+dy, pushUX, pushUY, -1.0)
+			}
+		}
+	}
+}
+
+// Step advances the simulation by a single time step
+func (s *Solver) Step() {
+	s.CollideThreaded()
+	s.SetBoundaries()
+	s.StreamThreaded()
+	s.ApplyZouHe()
+	if s.immiscible != nil {
+		s.streamImmiscible()
+	}
+	s.stepScalars()
+	s.time++
+	s.recordProbes()
+	s.advectTracers()
+}
+
+// Simulate advances the simulation by StepsPerFrame time steps. If drag is
+// not nil the fluid is pushed after every step.
+func (s *Solver) Simulate(drag *DragFluidProperties) {
+
+	// Execute a bunch of time steps:
+	for step := 0; step < s.stepsPerFrame; step++ {
+		s.Step()
+
+		if drag != nil {
+			s.DragFluid(drag.PushX, drag.PushY, drag.PushUX, drag.PushUY)
+		}
+	}
+
+	s.CheckFlowStability()
+}
+
+// Clear all barriers in the grid, every interior site becomes fluid
+func (s *Solver) ClearBarriers() {
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			s.cells[x+y*s.xdim] = FluidCell
+			s.cellBody[x+y*s.xdim] = -1
+			s.solidFraction[x+y*s.xdim] = 0
+		}
+	}
+	s.markEdges()
+	s.bodies = nil
+	s.forces = nil
+	s.lift = nil
+}
+
+// Create simple barrier
+func (s *Solver) CreateBarrier(barrierType int) {
+	// Linear Barrier
+	if barrierType == LINE {
+		barrierSize := 8
+		x := int(math.Ceil(float64(s.ydim / 3)))
+		id := s.AddBody(Body{CenterX: float32(x), CenterY: float32(s.ydim / 2), Size: float32(2*barrierSize + 1)})
+		for y := ((s.ydim / 2) - barrierSize); y <= ((s.ydim / 2) + barrierSize); y++ {
+			s.SetBodyBarrier(x, y, id)
+		}
+	} else if barrierType == CIRCLE {
+		// Circular Barrier
+		xo := int(math.Ceil(float64(s.ydim / 3)))
+		yo := s.ydim / 2
+		r := 6
+		s.AddShape(Circle{X: float32(xo), Y: float32(yo), R: float32(r) + 0.5})
+	} else if barrierType == HEATED {
+		// Hot cylinder in the middle of the enclosure
+		id := s.AddShape(Circle{X: float32(s.xdim / 2), Y: float32(s.ydim / 2), R: float32(s.ydim) / 10})
+		b := s.Body(id)
+		b.Heat = Heated
+		s.SetBody(id, b)
+	} else if barrierType == POROUS {
+		s.createPorousBlock()
+	}
+}
+
+func (s *Solver) CreateColorMap() {
+	// Set up the array of colors for plotting (mimicks matplotlib "jet" colormap):
+	// (Kludge: Index nColors+1 labels the color used for drawing barriers.)
+	// +2 for the barrier color
+	s.redList = make([]int, (s.nColors + 2))
+	s.greenList = make([]int, (s.nColors + 2))
+	s.blueList = make([]int, (s.nColors + 2))
+	for c := 0; c <= s.nColors; c++ {
+		var r, g, b int
+		if c < s.nColors/8 {
+			r = 0
+			g = 0
+			b = (255 * (c + s.nColors/8) / (s.nColors / 4))
+		} else if c < 3*s.nColors/8 {
+			r = 0
+			g = (255 * (c - s.nColors/8) / (s.nColors / 4))
+			b = 255
+		} else if c < 5*s.nColors/8 {
+			r = (255 * (c - 3*s.nColors/8) / (s.nColors / 4))
+			g = 255
+			b = 255 - r
+		} else if c < 7*s.nColors/8 {
+			r = 255
+			g = (255 * (7*s.nColors/8 - c) / (s.nColors / 4))
+			b = 0
+		} else {
+			r = (255 * (9*s.nColors/8 - c) / (s.nColors / 4))
+			g = 0
+			b = 0
+		}
+		s.redList[c] = r
+		s.greenList[c] = g
+		s.blueList[c] = b
+	}
+	// barriers are black
+	s.redList[s.nColors+1] = 0
+	s.greenList[s.nColors+1] = 0
+	s.blueList[s.nColors+1] = 0
+}
+
+// Compute the curl (actually times 2) of the macroscopic velocity field, for plotting:
+func (s *Solver) ComputeCurl() {
+	x0, x1 := s.xRange() // fluid sites only; leave non-periodic edges set to zero
+	y0, y1 := s.yRange()
+	for y := y0; y <= y1; y++ {
+		yn, ys := (y+1)%s.ydim, (y-1+s.ydim)%s.ydim
+		for x := x0; x <= x1; x++ {
+			xe, xw := (x+1)%s.xdim, (x-1+s.xdim)%s.xdim
+			s.curl[x+y*s.xdim] = s.uy[xe+y*s.xdim] - s.uy[xw+y*s.xdim] - s.ux[x+yn*s.xdim] + s.ux[x+ys*s.xdim]
+		}
+	}
+}
+
+// Plot the selected flow property to image
+func (s *Solver) PlotToImage(rgba *image.RGBA, plotType int) {
+	if plotType == PlotCurl {
+		s.ComputeCurl()
+	}
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			rgba.SetRGBA(x, y, s.siteColor(x, y, plotType))
+		}
+	}
+	s.drawStreamlines(rgba)
+	s.drawTracers(rgba)
+	s.drawProbes(rgba)
+}
+
+// Color of the site (x, y) in the plot of the flow property plotType
+func (s *Solver) siteColor(x, y, plotType int) image_color.RGBA {
+	var cIndex = 0
+	var contrast = float32(1.2)
+	if s.cells[x+y*s.xdim].Solid() {
+		cIndex = s.nColors + 1 // kludge for barrier color which isn't really part of color map
+	} else {
+		if plotType == PlotRho && s.shanChen && s.scLiquid > s.scVapour {
+			// vapour to liquid across the whole color map
+			cIndex = int(float32(s.nColors) * (s.rho[x+y*s.xdim] - s.scVapour) / (s.scLiquid - s.scVapour))
+		} else if plotType == PlotRho {
+			cIndex = int(float32(s.nColors) * ((s.rho[x+y*s.xdim]-float32(1))*float32(6)*float32(contrast) + float32(0.5)))
+		} else if plotType == PlotUx {
+			cIndex = int(float32(s.nColors) * ((s.ux[x+y*s.xdim] * float32(2.0) * contrast) + float32(0.5)))
+		} else if plotType == PlotUy {
+			cIndex = int(float32(s.nColors) * ((s.uy[x+y*s.xdim] * float32(2.0) * contrast) + float32(0.5)))
+		} else if plotType == PlotSpeed {
+			speed := float32(math.Sqrt(float64(s.ux[x+y*s.xdim]*s.ux[x+y*s.xdim] + s.uy[x+y*s.xdim]*s.uy[x+y*s.xdim])))
+			cIndex = int(float32(s.nColors) * (speed * float32(4) * float32(contrast)))
+		} else if plotType == PlotViscosity {
+			// ratio of effective to molecular viscosity
+			cIndex = int(float32(s.nColors) * ((s.visc[x+y*s.xdim]/s.flowVisc - float32(1)) * float32(0.25) * contrast))
+		} else if plotType == PlotScalar {
+			cIndex = 0
+			if s.scalar != nil {
+				cIndex = int(float32(s.nColors) * s.scalar.c[x+y*s.xdim])
+			}
+		} else if plotType == PlotTemperature {
+			cIndex = 0
+			if s.thermal != nil {
+				cIndex = int(float32(s.nColors) * s.thermal.c[x+y*s.xdim])
+			}
+		} else if plotType == PlotPhase {
+			cIndex = 0
+			if s.immiscible != nil {
+				cIndex = int(float32(s.nColors) * (s.immiscible.phase[x+y*s.xdim] + 1) / 2)
+			}
+		} else {
+			cIndex = int(float32(s.nColors) * (s.curl[x+y*s.xdim]*float32(5)*float32(contrast) + float32(0.5)))
+		}
+
+		if cIndex < 0 {
+			cIndex = 0
+		}
+		if cIndex > s.nColors {
+			cIndex = s.nColors
+		}
+	}
+	c := image_color.RGBA{uint8(s.redList[cIndex]), uint8(s.greenList[cIndex]), uint8(s.blueList[cIndex]), 255}
+	if s.cells[x+y*s.xdim] == PorousCell {
+		// darken porous sites towards the barrier color
+		shade := 1 - 0.5*min(4*s.solidFraction[x+y*s.xdim], 1)
+		c.R, c.G, c.B = uint8(float32(c.R)*shade), uint8(float32(c.G)*shade), uint8(float32(c.B)*shade)
+	}
+	return c
+}
+
+type empty1 struct{}
+
+// PlotToImageThreaded plots the selected flow property like PlotToImage,
+// coloring the rows in parallel
+func (s *Solver) PlotToImageThreaded(rgba *image.RGBA, plotType int) {
+	if plotType == PlotCurl {
+		s.ComputeCurl()
+	}
+
+	sem := make(chan empty1, s.ydim)
+
+	for yy := 0; yy < s.ydim; yy++ {
+		go func(y int) {
+			for x := 0; x < s.xdim; x++ {
+				rgba.SetRGBA(x, y, s.siteColor(x, y, plotType))
+			}
+			sem <- empty1{}
+		}(yy)
+	}
+
+	for y := 0; y < s.ydim; y++ {
+		<-sem
+	}
+	s.drawStreamlines(rgba)
+	s.drawTracers(rgba)
+	s.drawProbes(rgba)
+}
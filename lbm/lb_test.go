@@ -0,0 +1,26 @@
+package lbm
+
+import (
+	"image"
+	"testing"
+)
+
+func TestThreadedPlotMatchesPlot(t *testing.T) {
+	s := CreateSolver(64, 32, 0.1, 0.03)
+	s.InitalizeLattice(64, 32, 0.1, 0.03, CIRCLE)
+	for step := 0; step < 100; step++ {
+		s.Step()
+	}
+	for plot := 0; plot < NumPlotTypes; plot++ {
+		want := image.NewRGBA(image.Rect(0, 0, 64, 32))
+		got := image.NewRGBA(image.Rect(0, 0, 64, 32))
+		s.PlotToImage(want, plot)
+		s.PlotToImageThreaded(got, plot)
+		for i := range want.Pix {
+			if got.Pix[i] != want.Pix[i] {
+				t.Errorf("plot %d: threaded image differs at pixel %d", plot, i/4)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package lbm
+
+// Transformation from populations to the moments of Lallemand and Luo,
+// ordered as density, energy, energy squared, x momentum, x energy flux,
+// y momentum, y energy flux and the two components of the stress tensor.
+var mrtM = [Q][Q]float32{
+	{1, 1, 1, 1, 1, 1, 1, 1, 1},
+	{-4, -1, -1, -1, -1, 2, 2, 2, 2},
+	{4, -2, -2, -2, -2, 1, 1, 1, 1},
+	{0, 1, 0, -1, 0, 1, -1, -1, 1},
+	{0, -2, 0, 2, 0, 1, -1, -1, 1},
+	{0, 0, 1, 0, -1, 1, 1, -1, -1},
+	{0, 0, -2, 0, 2, 1, 1, -1, -1},
+	{0, 1, -1, 1, -1, 0, 0, 0, 0},
+	{0, 0, 0, 0, 0, 1, -1, 1, -1},
+}
+
+// The rows of mrtM are orthogonal, so its inverse is its transpose scaled
+// by the reciprocal squared norm of each row
+var mrtNorm = [Q]float32{1.0 / 9, 1.0 / 36, 1.0 / 36, 1.0 / 6, 1.0 / 12, 1.0 / 6, 1.0 / 12, 1.0 / 4, 1.0 / 4}
+
+// MRT is the multiple relaxation time operator of Lallemand and Luo. The
+// stress moments relax with omega, the remaining non-conserved moments
+// relax with their own rates, which damps the modes that drive BGK
+// unstable at low viscosity.
+type MRT struct {
+	// Relaxation rate of the energy moment, sets the bulk viscosity
+	SBulk float32
+	// Relaxation rate of the energy squared moment
+	SEnergy float32
+	// Relaxation rate of the energy flux (ghost) moments
+	SGhost float32
+}
+
+// NewMRT creates an MRT operator with the rates suggested by Lallemand and Luo
+func NewMRT() *MRT {
+	return &MRT{SBulk: 1.64, SEnergy: 1.54, SGhost: 1.9}
+}
+
+func (m *MRT) Name() string {
+	return "MRT"
+}
+
+func (m *MRT) Collide(f *[Q]float32, rho, ux, uy, omega float32) {
+	var mom [Q]float32
+	for k := 0; k < Q; k++ {
+		for q := 0; q < Q; q++ {
+			mom[k] += mrtM[k][q] * f[q]
+		}
+	}
+
+	jx := rho * ux
+	jy := rho * uy
+	j2 := (jx*jx + jy*jy) / rho
+
+	// Relax each non-conserved moment towards its equilibrium
+	var dm [Q]float32
+	dm[1] = -m.SBulk * (mom[1] - (-2*rho + 3*j2))
+	dm[2] = -m.SEnergy * (mom[2] - (rho - 3*j2))
+	dm[4] = -m.SGhost * (mom[4] + jx)
+	dm[6] = -m.SGhost * (mom[6] + jy)
+	dm[7] = -omega * (mom[7] - (jx*jx-jy*jy)/rho)
+	dm[8] = -omega * (mom[8] - jx*jy/rho)
+
+	for q := 0; q < Q; q++ {
+		var df float32
+		for k := 1; k < Q; k++ {
+			df += mrtM[k][q] * mrtNorm[k] * dm[k]
+		}
+		f[q] += df
+	}
+}
+
+func init() {
+	RegisterCollisionOperator("mrt", func() CollisionOperator { return NewMRT() })
+}
@@ -0,0 +1,26 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMRTRatesLeaveTheShearViscosity(t *testing.T) {
+	tests := []struct {
+		name                string
+		bulk, energy, ghost float32
+	}{
+		{"defaults", 1.64, 1.54, 1.9},
+		{"ghost 1", 1.64, 1.54, 1},
+		{"ghost 1.5", 1.64, 1.54, 1.5},
+		{"all 1.2", 1.2, 1.2, 1.2},
+	}
+	const visc = 0.02
+	for _, tt := range tests {
+		op := &MRT{SBulk: tt.bulk, SEnergy: tt.energy, SGhost: tt.ghost}
+		want := Viscosity(Omega(visc))
+		if got := shearWaveViscosity(t, op, visc); math.Abs(float64(got-want)) > 0.03*float64(want) {
+			t.Errorf("%s: shear wave decays at viscosity %g, want %g", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package lbm
+
+import (
+	"math"
+	"math/rand"
+)
+
+// EnableShanChen turns the fluid into a single component that separates
+// into liquid and vapour, with the Shan-Chen pseudopotential
+// psi = 1 - exp(-rho). Neighbouring sites attract each other with the force
+// -G psi(x) sum w psi(x + c) c, which separates the phases for interaction
+// strengths G below -4. The barriers are wetted according to the contact
+// angle, see SetContactAngle. Strong interactions need a high viscosity to
+// stay stable: G = -5.5 runs with a viscosity of 1/6, at 0.03 only G = -4.5
+// does.
+func (s *Solver) EnableShanChen(g float32) {
+	s.shanChen = true
+	s.SetShanChenStrength(g)
+}
+
+// DisableShanChen turns the interaction between sites off
+func (s *Solver) DisableShanChen() {
+	s.shanChen = false
+	s.psi = nil
+}
+
+// ShanChenEnabled reports whether the Shan-Chen interaction is applied
+func (s *Solver) ShanChenEnabled() bool {
+	return s.shanChen
+}
+
+// SetShanChenStrength sets the interaction strength G, more negative
+// values give denser liquids and thinner vapours
+func (s *Solver) SetShanChenStrength(g float32) {
+	s.scStrength = g
+	s.scVapour, s.scLiquid = coexistence(float64(g))
+	s.updateWallDensity()
+}
+
+func (s *Solver) ShanChenStrength() float32 {
+	return s.scStrength
+}
+
+// SetContactAngle sets the wettability of the barriers through the contact
+// angle, in degrees, a liquid makes with them, 90 by default. Barrier sites
+// act as fluid with a density following the fluid next to them, denser for
+// wetting barriers. The angle is reproduced to within about 10 degrees for
+// angles between 60 and 120 and interaction strengths between -5 and -5.5.
+// Further out it drifts away from 90: 30 comes out near 40, and 150 near
+// 160. Weaker interactions get the wettability fitted at -5, and at -4.5 a
+// drop no longer keeps its angle but spreads into a film on wetting
+// barriers and lifts off the others.
+func (s *Solver) SetContactAngle(degrees float32) {
+	s.contactAngle = degrees
+	s.updateWallDensity()
+}
+
+func (s *Solver) ContactAngle() float32 {
+	return s.contactAngle
+}
+
+// CoexistenceDensities returns the densities of the vapour and the liquid
+// in equilibrium at the current interaction strength, both 0 without phase
+// separation
+func (s *Solver) CoexistenceDensities() (float32, float32) {
+	return s.scVapour, s.scLiquid
+}
+
+// Derive the wettability of the barriers from the contact angle. The
+// density of a barrier site is that of the fluid next to it scaled up by
+// 1 + a cos theta for wetting angles, or lowered by b |cos theta| otherwise
+// (Li et al. 2014). The slopes a and b were fitted to drops resting on a
+// flat wall at G = -5 and -5.5, and are interpolated between the two.
+func (s *Solver) updateWallDensity() {
+	t := min(max((-5-s.scStrength)/0.5, 0), 1)
+	cos := float32(math.Cos(float64(s.contactAngle) * math.Pi / 180))
+	if cos >= 0 {
+		s.scWetting = (0.6 + 0.2*t) * cos
+	} else {
+		s.scWetting = (0.39 + 0.25*t) * cos
+	}
+}
+
+// Density a barrier site takes from the mean density rho of the fluid next
+// to it
+func (s *Solver) wallDensity(rho float32) float32 {
+	if s.scWetting >= 0 {
+		return rho * (1 + s.scWetting)
+	}
+	return max(rho+s.scWetting, 0)
+}
+
+// Pseudopotential of the density rho
+func pseudopotential(rho float64) float64 {
+	return 1 - math.Exp(-rho)
+}
+
+// Pressure of the Shan-Chen fluid with interaction strength g
+func shanChenPressure(rho, g float64) float64 {
+	psi := pseudopotential(rho)
+	return rho/3 + g*psi*psi/6
+}
+
+// Densities of the vapour and the liquid in equilibrium, from the equal
+// pressure and the mechanical stability condition, integral of
+// (p0 - p) psi' / psi between them being 0. The weight is psi' / psi^2 for
+// the velocity shift forcing of Shan and Chen (1994), Guo forcing lowers the
+// power of psi by one (Li, Luo and Li 2012).
+func coexistence(g float64) (float32, float32) {
+	const rhoMax, n = 10.0, 4000
+	// spinodal densities, where the pressure peaks and dips
+	var lo, hi float64
+	prev := shanChenPressure(rhoMax/n, g)
+	rising := true
+	for k := 2; k <= n; k++ {
+		rho := rhoMax * float64(k) / n
+		p := shanChenPressure(rho, g)
+		if rising && p < prev {
+			lo, rising = rho, false
+		} else if !rising && p > prev {
+			hi = rho
+			break
+		}
+		prev = p
+	}
+	if hi == 0 {
+		return 0, 0
+	}
+	pmin := math.Max(shanChenPressure(hi, g), 0)
+	pmax := shanChenPressure(lo, g)
+
+	// density below a (or above b) at which the pressure equals p0
+	root := func(a, b, p0 float64) float64 {
+		for k := 0; k < 60; k++ {
+			m := (a + b) / 2
+			if (shanChenPressure(m, g) < p0) == (shanChenPressure(a, g) < p0) {
+				a = m
+			} else {
+				b = m
+			}
+		}
+		return (a + b) / 2
+	}
+	var vapour, liquid float64
+	for k := 0; k < 60; k++ {
+		p0 := (pmin + pmax) / 2
+		vapour = root(1e-9, lo, p0)
+		liquid = root(hi, rhoMax, p0)
+		// Simpson's rule
+		var sum float64
+		const m = 400
+		h := (liquid - vapour) / m
+		for j := 0; j <= m; j++ {
+			rho := vapour + float64(j)*h
+			psi := pseudopotential(rho)
+			f := (p0 - shanChenPressure(rho, g)) * math.Exp(-rho) / psi
+			switch {
+			case j == 0 || j == m:
+				sum += f
+			case j%2 == 1:
+				sum += 4 * f
+			default:
+				sum += 2 * f
+			}
+		}
+		if sum > 0 {
+			pmax = p0
+		} else {
+			pmin = p0
+		}
+	}
+	return float32(vapour), float32(liquid)
+}
+
+// Compute the pseudopotential of every site before the collision, barrier
+// sites take the wall density
+func (s *Solver) updatePsi() {
+	if len(s.psi) != s.numElements {
+		s.psi = make([]float32, s.numElements)
+	}
+	var f [Q]float32
+	for i := range s.psi {
+		if s.cells[i].Solid() {
+			continue
+		}
+		s.gather(i, &f)
+		var rho float32
+		for q := 0; q < Q; q++ {
+			rho += f[q]
+		}
+		s.rho[i] = rho
+		s.psi[i] = float32(pseudopotential(float64(rho)))
+	}
+	for i := range s.psi {
+		if !s.cells[i].Solid() {
+			continue
+		}
+		x, y := i%s.xdim, i/s.xdim
+		var rho, w float32
+		for q := 1; q < Q; q++ {
+			nx := (x + int(Cx[q]) + s.xdim) % s.xdim
+			ny := (y + int(Cy[q]) + s.ydim) % s.ydim
+			if n := nx + ny*s.xdim; !s.cells[n].Solid() {
+				rho += W[q] * s.rho[n]
+				w += W[q]
+			}
+		}
+		s.psi[i] = 0
+		if w > 0 {
+			s.psi[i] = float32(pseudopotential(float64(s.wallDensity(rho / w))))
+		}
+	}
+}
+
+// Shan-Chen interaction force on the fluid at site i
+func (s *Solver) shanChenForce(i int) (float32, float32) {
+	if !s.shanChen {
+		return 0, 0
+	}
+	x, y := i%s.xdim, i/s.xdim
+	var fx, fy float32
+	for q := 1; q < Q; q++ {
+		nx := (x + int(Cx[q]) + s.xdim) % s.xdim
+		ny := (y + int(Cy[q]) + s.ydim) % s.ydim
+		psi := s.psi[nx+ny*s.xdim]
+		fx += W[q] * psi * Cx[q]
+		fy += W[q] * psi * Cy[q]
+	}
+	g := -s.scStrength * s.psi[i]
+	return g * fx, g * fy
+}
+
+// SetChannelBoundaries sets up a channel between walls at the bottom and
+// the top that wraps around along x
+func (s *Solver) SetChannelBoundaries() {
+	s.SetBoundary(Left, Periodic)
+	s.SetBoundary(Right, Periodic)
+	s.SetBoundary(Bottom, NoSlipWall)
+	s.SetBoundary(Top, NoSlipWall)
+}
+
+// SetPeriodicBoundaries makes the domain wrap around along both axes
+func (s *Solver) SetPeriodicBoundaries() {
+	for edge := 0; edge < NumEdges; edge++ {
+		s.SetBoundary(edge, Periodic)
+	}
+}
+
+// Fill the domain with liquid and vapour at rest: a half drop of liquid on
+// the bottom wall for DROP, and a mixture about to separate for PHASES
+func (s *Solver) initMultiphase() {
+	vapour, liquid := s.scVapour, s.scLiquid
+	if !s.shanChen || liquid == 0 {
+		return
+	}
+	r := float64(s.ydim) / 3
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			var rho float32
+			if s.barrierType == DROP {
+				d := math.Hypot(float64(x)-float64(s.xdim)/2, float64(y)-0.5)
+				wet := (1 - math.Tanh((d-r)/2.5)) / 2
+				rho = vapour + float32(wet)*(liquid-vapour)
+			} else {
+				rho = (vapour+liquid)/2 + 0.01*(liquid-vapour)*float32(rnd.Float64()-0.5)
+			}
+			s.SetEquilibrium(x, y, 0, 0, rho)
+		}
+	}
+}
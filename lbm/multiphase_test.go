@@ -0,0 +1,91 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShanChenFlatInterfaceCoexistence(t *testing.T) {
+	const xdim, ydim, visc = 4, 64, 1.0 / 6
+	for _, g := range []float32{-4.5, -5, -5.5} {
+		s := CreateSolver(xdim, ydim, 0, visc)
+		s.SetPeriodicBoundaries()
+		s.InitalizeLattice(xdim, ydim, 0, visc, EMPTY)
+		s.EnableShanChen(g)
+		vapour, liquid := s.CoexistenceDensities()
+		// a liquid layer across the middle, both phases off their
+		// equilibrium densities
+		for y := 0; y < ydim; y++ {
+			rho := 1.2 * vapour
+			if y >= ydim/4 && y < 3*ydim/4 {
+				rho = 0.95 * liquid
+			}
+			for x := 0; x < xdim; x++ {
+				s.SetEquilibrium(x, y, 0, 0, rho)
+			}
+		}
+		for step := 0; step < 5000; step++ {
+			s.Step()
+		}
+		gotVapour, gotLiquid := s.rho[s.Index(0, 0)], s.rho[s.Index(0, ydim/2)]
+		if math.Abs(float64(gotVapour-vapour)) > 0.03*float64(vapour) {
+			t.Errorf("G %g: vapour density %g, want %g", g, gotVapour, vapour)
+		}
+		if math.Abs(float64(gotLiquid-liquid)) > 0.01*float64(liquid) {
+			t.Errorf("G %g: liquid density %g, want %g", g, gotLiquid, liquid)
+		}
+	}
+}
+
+// Contact angle of the drop resting on the bottom wall, at y = 0.5, from the
+// circle through the top of the drop on the middle column and the edges of
+// the drop a few rows above the wall, where the interface is at the mean of
+// the coexistence densities
+func dropAngle(s *Solver) float64 {
+	vapour, liquid := s.CoexistenceDensities()
+	mid := (vapour + liquid) / 2
+	crossing := func(a, b float32) float64 {
+		return float64((a - mid) / (a - b))
+	}
+	xc := s.xdim / 2
+	var top float64
+	for y := 1; y < s.ydim-1; y++ {
+		a, b := s.rho[s.Index(xc, y)], s.rho[s.Index(xc, y+1)]
+		if a >= mid && b < mid {
+			top = float64(y) + crossing(a, b)
+			break
+		}
+	}
+	const row = 4
+	var halfWidth float64
+	for x := xc; x < s.xdim-1; x++ {
+		a, b := s.rho[s.Index(x, row)], s.rho[s.Index(x+1, row)]
+		if a >= mid && b < mid {
+			halfWidth = float64(x-xc) + crossing(a, b)
+			break
+		}
+	}
+	centre := (top*top - row*row - halfWidth*halfWidth) / (2 * (top - row))
+	return math.Acos((0.5-centre)/(top-centre)) * 180 / math.Pi
+}
+
+func TestShanChenDropContactAngle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("drops take thousands of steps to settle")
+	}
+	const xdim, ydim, visc = 80, 40, 1.0 / 6
+	for _, g := range []float32{-5, -5.5} {
+		for _, angle := range []float32{60, 90, 120} {
+			s := CreateSolver(xdim, ydim, 0, visc)
+			s.EnableShanChen(g)
+			s.SetContactAngle(angle)
+			s.InitalizeLattice(xdim, ydim, 0, visc, DROP)
+			for step := 0; step < 4000; step++ {
+				s.Step()
+			}
+			if got := dropAngle(s); math.Abs(got-float64(angle)) > 10 {
+				t.Errorf("G %g: contact angle %.1f, want %g", g, got, angle)
+			}
+		}
+	}
+}
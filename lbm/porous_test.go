@@ -0,0 +1,35 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPorousSlabPressureDrop(t *testing.T) {
+	const xdim, ydim, vel, visc, length = 60, 8, 0.005, 0.1, 10
+	for _, ns := range []float32{0.01, 0.05, 0.1} {
+		// plug flow between slip walls through a slab across the channel
+		s := CreateSolver(xdim, ydim, vel, visc)
+		s.SetBoundary(Left, ZouHeVelocity)
+		s.SetBoundary(Right, ZouHePressure)
+		s.SetBoundary(Bottom, FreeSlipWall)
+		s.SetBoundary(Top, FreeSlipWall)
+		s.InitalizeLattice(xdim, ydim, vel, visc, EMPTY)
+		for y := 1; y < ydim-1; y++ {
+			for x := 20; x < 20+length; x++ {
+				s.SetSolidFraction(x, y, ns)
+			}
+		}
+		for step := 0; step < 4000; step++ {
+			s.Step()
+		}
+		// Darcy's law with the permeability (1 - ns) nu / (2 ns) of Walsh et
+		// al. (2009), for the mass flux through each row
+		j := s.MassFlux(Left) / (ydim - 2)
+		want := 2 * ns / (1 - ns) * j * length
+		got, ok := s.PorousPressureDrop()
+		if !ok || math.Abs(float64(got-want)) > 0.02*float64(want) {
+			t.Errorf("solid fraction %g: pressure drop %g (%v), want %g", ns, got, ok, want)
+		}
+	}
+}
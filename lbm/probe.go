@@ -0,0 +1,158 @@
+package lbm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	image_color "image/color"
+	"io"
+)
+
+// ProbeHistoryLength is the number of time steps recorded by every probe
+const ProbeHistoryLength = 4096
+
+// ProbeSample holds the flow at a probe after one time step
+type ProbeSample struct {
+	Time int
+	Rho  float32
+	Ux   float32
+	Uy   float32
+	Curl float32
+}
+
+// A point probe with its samples in a ring buffer
+type probe struct {
+	x, y    int
+	samples []ProbeSample
+	pos     int
+	n       int
+}
+
+// AddProbe places a probe on the site (x, y) and returns its ID, or -1 if
+// the site is outside the grid
+func (s *Solver) AddProbe(x, y int) int {
+	if x < 0 || x >= s.xdim || y < 0 || y >= s.ydim {
+		return -1
+	}
+	s.probes = append(s.probes, &probe{x: x, y: y, samples: make([]ProbeSample, ProbeHistoryLength)})
+	return len(s.probes) - 1
+}
+
+// RemoveProbe removes a probe, the IDs of the probes after it shift down by one
+func (s *Solver) RemoveProbe(id int) {
+	s.probes = append(s.probes[:id], s.probes[id+1:]...)
+}
+
+// ClearProbes removes all probes
+func (s *Solver) ClearProbes() {
+	s.probes = nil
+}
+
+// NumProbes returns the number of probes
+func (s *Solver) NumProbes() int {
+	return len(s.probes)
+}
+
+// ProbePosition returns the site a probe is on
+func (s *Solver) ProbePosition(id int) (int, int) {
+	return s.probes[id].x, s.probes[id].y
+}
+
+// MoveProbe moves a probe to the site (x, y), clamped to the grid. The
+// samples recorded at the old site are discarded.
+func (s *Solver) MoveProbe(id, x, y int) {
+	p := s.probes[id]
+	x = min(max(x, 0), s.xdim-1)
+	y = min(max(y, 0), s.ydim-1)
+	if x == p.x && y == p.y {
+		return
+	}
+	p.x, p.y = x, y
+	p.pos, p.n = 0, 0
+}
+
+// ProbeAt returns the ID of the probe closest to (x, y) within radius
+// sites, or -1 if there is none
+func (s *Solver) ProbeAt(x, y, radius int) int {
+	best, bestD := -1, radius*radius
+	for id, p := range s.probes {
+		dx, dy := p.x-x, p.y-y
+		if d := dx*dx + dy*dy; d <= bestD {
+			best, bestD = id, d
+		}
+	}
+	return best
+}
+
+// ProbeHistory returns the samples recorded by a probe, oldest first
+func (s *Solver) ProbeHistory(id int) []ProbeSample {
+	p := s.probes[id]
+	h := make([]ProbeSample, p.n)
+	start := p.pos - p.n
+	if start < 0 {
+		start += ProbeHistoryLength
+	}
+	for i := range h {
+		h[i] = p.samples[(start+i)%ProbeHistoryLength]
+	}
+	return h
+}
+
+// WriteProbeCSV writes the samples of every probe as CSV
+func (s *Solver) WriteProbeCSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "probe,x,y,step,rho,ux,uy,curl")
+	for id, p := range s.probes {
+		for _, v := range s.ProbeHistory(id) {
+			fmt.Fprintf(bw, "%d,%d,%d,%d,%g,%g,%g,%g\n", id, p.x, p.y, v.Time, v.Rho, v.Ux, v.Uy, v.Curl)
+		}
+	}
+	return bw.Flush()
+}
+
+// Record the flow at every probe
+func (s *Solver) recordProbes() {
+	for _, p := range s.probes {
+		i := p.x + p.y*s.xdim
+		xe, xw := (p.x+1)%s.xdim, (p.x-1+s.xdim)%s.xdim
+		yn, ys := (p.y+1)%s.ydim, (p.y-1+s.ydim)%s.ydim
+		curl := s.uy[xe+p.y*s.xdim] - s.uy[xw+p.y*s.xdim] - s.ux[p.x+yn*s.xdim] + s.ux[p.x+ys*s.xdim]
+		p.samples[p.pos] = ProbeSample{Time: s.time, Rho: s.rho[i], Ux: s.ux[i], Uy: s.uy[i], Curl: curl}
+		p.pos = (p.pos + 1) % ProbeHistoryLength
+		if p.n < ProbeHistoryLength {
+			p.n++
+		}
+	}
+}
+
+// Mark every probe with a white square in a black frame
+func (s *Solver) drawProbes(rgba *image.RGBA) {
+	white := image_color.RGBA{255, 255, 255, 255}
+	black := image_color.RGBA{0, 0, 0, 255}
+	for _, p := range s.probes {
+		for r := 2; r <= 3; r++ {
+			c := white
+			if r == 3 {
+				c = black
+			}
+			for d := -r; d <= r; d++ {
+				rgba.SetRGBA(p.x+d, p.y-r, c)
+				rgba.SetRGBA(p.x+d, p.y+r, c)
+				rgba.SetRGBA(p.x-r, p.y+d, c)
+				rgba.SetRGBA(p.x+r, p.y+d, c)
+			}
+		}
+	}
+}
+
+// Drop the probes that fall outside a new grid and discard all samples
+func (s *Solver) resetProbes() {
+	kept := s.probes[:0]
+	for _, p := range s.probes {
+		if p.x < s.xdim && p.y < s.ydim {
+			p.pos, p.n = 0, 0
+			kept = append(kept, p)
+		}
+	}
+	s.probes = kept
+}
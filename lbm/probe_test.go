@@ -0,0 +1,97 @@
+package lbm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAddProbeRejectsSitesOffTheGrid(t *testing.T) {
+	tests := []struct {
+		x, y int
+		ok   bool
+	}{
+		{0, 0, true},
+		{63, 31, true},
+		{-1, 10, false},
+		{10, -1, false},
+		{64, 10, false},
+		{10, 32, false},
+	}
+	for _, tt := range tests {
+		s := CreateSolver(64, 32, 0.1, 0.03)
+		s.InitalizeLattice(64, 32, 0.1, 0.03, EMPTY)
+		if id := s.AddProbe(tt.x, tt.y); (id >= 0) != tt.ok {
+			t.Errorf("probe at %d,%d: ID %d, want it placed %v", tt.x, tt.y, id, tt.ok)
+		}
+	}
+}
+
+func TestProbeAtRespectsRadius(t *testing.T) {
+	s := CreateSolver(64, 32, 0.1, 0.03)
+	s.InitalizeLattice(64, 32, 0.1, 0.03, EMPTY)
+	s.AddProbe(10, 10)
+	s.AddProbe(20, 10)
+	tests := []struct {
+		x, y, radius int
+		want         int
+	}{
+		{10, 10, 0, 0},
+		{13, 14, 5, 0},
+		{13, 14, 4, -1},
+		{16, 10, 5, 1},
+		{15, 20, 3, -1},
+	}
+	for _, tt := range tests {
+		if got := s.ProbeAt(tt.x, tt.y, tt.radius); got != tt.want {
+			t.Errorf("ProbeAt(%d, %d, %d) = %d, want %d", tt.x, tt.y, tt.radius, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProbeCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps int
+		move  bool
+		rows  int // rows per probe
+		last  string
+	}{
+		{"no steps", 0, false, 0, ""},
+		{"some steps", 25, false, 25, "1,8,2,25,"},
+		{"moved", 25, true, 10, "1,9,2,25,"},
+		{"full history", ProbeHistoryLength + 5, false, ProbeHistoryLength, "1,8,2,4101,"},
+	}
+	for _, tt := range tests {
+		s := CreateSolver(16, 8, 0.1, 0.03)
+		s.InitalizeLattice(16, 8, 0.1, 0.03, EMPTY)
+		s.AddProbe(4, 4)
+		s.AddProbe(8, 2)
+		for step := 0; step < tt.steps; step++ {
+			if tt.move && step == tt.steps-10 {
+				s.MoveProbe(0, 100, -3)
+				s.MoveProbe(1, 9, 2)
+			}
+			s.Step()
+		}
+		var buf bytes.Buffer
+		if err := s.WriteProbeCSV(&buf); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if lines[0] != "probe,x,y,step,rho,ux,uy,curl" {
+			t.Errorf("%s: header %q", tt.name, lines[0])
+		}
+		if got := len(lines) - 1; got != 2*tt.rows {
+			t.Errorf("%s: %d rows, want %d", tt.name, got, 2*tt.rows)
+		}
+		if tt.move {
+			if x, y := s.ProbePosition(0); x != 15 || y != 0 {
+				t.Errorf("%s: probe moved off the grid is at %d,%d, want it clamped to 15,0", tt.name, x, y)
+			}
+		}
+		if last := lines[len(lines)-1]; tt.rows > 0 && !strings.HasPrefix(last, tt.last) {
+			t.Errorf("%s: last row %q, want it to start with %q", tt.name, last, tt.last)
+		}
+	}
+}
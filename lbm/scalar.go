@@ -0,0 +1,248 @@
+package lbm
+
+// D2Q5 lattice of the advection-diffusion solvers: rest, east, north, west
+// and south
+const q5 = 5
+
+var (
+	cx5  = [q5]int{0, 1, 0, -1, 0}
+	cy5  = [q5]int{0, 0, 1, 0, -1}
+	w5   = [q5]float32{1.0 / 3.0, 1.0 / 6.0, 1.0 / 6.0, 1.0 / 6.0, 1.0 / 6.0}
+	opp5 = [q5]int{0, 3, 4, 1, 2}
+)
+
+// adField is a scalar carried by the flow and diffusing, solved with its
+// own populations on a D2Q5 lattice
+type adField struct {
+	g     [q5][]float32
+	spare [q5][]float32
+	c     []float32 // value of the scalar at every site
+
+	diffusivity float32
+	inlet       float32 // value entering through inlet sites
+	source      float32 // value held at source sites
+
+	// Hot and cold walls hold the hot and cold temperatures, other walls
+	// are insulated
+	thermal bool
+	hotFlux float32 // heat entering the fluid from hot walls in the last step
+}
+
+func newADField(n int, diffusivity float32) *adField {
+	f := &adField{c: make([]float32, n), diffusivity: diffusivity}
+	for q := 0; q < q5; q++ {
+		f.g[q] = make([]float32, n)
+		f.spare[q] = make([]float32, n)
+	}
+	return f
+}
+
+// Set the populations of site i to equilibrium with the value c
+func (f *adField) setEquilibrium(i int, c, ux, uy float32) {
+	for q := 0; q < q5; q++ {
+		f.g[q][i] = w5[q] * c * (1 + 3*(float32(cx5[q])*ux+float32(cy5[q])*uy))
+	}
+	f.c[i] = c
+}
+
+// Value held by the walls of type c, false for insulated walls
+func (f *adField) wallValue(c CellType) (float32, bool) {
+	if f.thermal && c == HotWallCell {
+		return hotTemperature, true
+	}
+	if f.thermal && c == ColdWallCell {
+		return coldTemperature, true
+	}
+	return 0, false
+}
+
+// Fill the whole field with the value c, in equilibrium with the flow
+func (s *Solver) resetADField(f *adField, c float32) {
+	for i := range f.c {
+		f.setEquilibrium(i, c, s.ux[i], s.uy[i])
+	}
+}
+
+// Relax the scalar towards equilibrium with the velocity of the fluid,
+// source sites are held at the source value
+func (s *Solver) collideADField(f *adField) {
+	omega := 1 / (3*f.diffusivity + 0.5)
+	x0, x1 := s.xRange()
+	y0, y1 := s.yRange()
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			i := x + y*s.xdim
+			switch s.cells[i] {
+			case WallCell, MovingWallCell, SlipWallCell, HotWallCell, ColdWallCell:
+				if v, ok := f.wallValue(s.cells[i]); ok {
+					f.c[i] = v
+				}
+				continue
+			case SourceCell:
+				f.setEquilibrium(i, f.source, s.ux[i], s.uy[i])
+				continue
+			}
+			var c float32
+			for q := 0; q < q5; q++ {
+				c += f.g[q][i]
+			}
+			f.c[i] = c
+			for q := 0; q < q5; q++ {
+				eq := w5[q] * c * (1 + 3*(float32(cx5[q])*s.ux[i]+float32(cy5[q])*s.uy[i]))
+				f.g[q][i] -= omega * (f.g[q][i] - eq)
+			}
+		}
+	}
+}
+
+// Fill the edge sites of the scalar, inlets carry the inlet value in and
+// outlets copy the sites next to them
+func (s *Solver) setADFieldEdges(f *adField) {
+	edge := func(e, x, y int) {
+		i := x + y*s.xdim
+		switch s.cells[i] {
+		case HotWallCell, ColdWallCell:
+			if v, ok := f.wallValue(s.cells[i]); ok {
+				f.c[i] = v
+			}
+		case InletCell:
+			f.setEquilibrium(i, f.inlet, s.ux[i], s.uy[i])
+		case OutletCell:
+			in := x + edgeNx[e] + (y+edgeNy[e])*s.xdim
+			for q := 0; q < q5; q++ {
+				f.g[q][i] = f.g[q][in]
+			}
+			f.c[i] = f.c[in]
+		}
+	}
+	if !s.PeriodicX() {
+		y0, y1 := s.yRange()
+		for y := y0; y <= y1; y++ {
+			edge(Left, 0, y)
+			edge(Right, s.xdim-1, y)
+		}
+	}
+	if !s.PeriodicY() {
+		for x := 0; x < s.xdim; x++ {
+			edge(Bottom, x, 0)
+			edge(Top, x, s.ydim-1)
+		}
+	}
+}
+
+// Pull the scalar populations from the upstream sites. Populations that
+// would come from a solid site are bounced back, so walls are insulated,
+// except for the hot and cold walls of a temperature field. Those are
+// bounced back with the opposite sign, which holds the wall temperature
+// halfway along the link.
+func (s *Solver) streamADField(f *adField) {
+	s.setADFieldEdges(f)
+	f.hotFlux = 0
+	x0, x1 := s.xRange()
+	y0, y1 := s.yRange()
+	for q := 0; q < q5; q++ {
+		copy(f.spare[q], f.g[q])
+	}
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			i := x + y*s.xdim
+			if s.cells[i].Solid() {
+				continue
+			}
+			for q := 1; q < q5; q++ {
+				sx, sy := x-cx5[q], y-cy5[q]
+				if sx < 0 {
+					sx += s.xdim
+				} else if sx >= s.xdim {
+					sx -= s.xdim
+				}
+				if sy < 0 {
+					sy += s.ydim
+				} else if sy >= s.ydim {
+					sy -= s.ydim
+				}
+				src := sx + sy*s.xdim
+				out := f.g[opp5[q]][i] // population that left towards the upstream site
+				c := s.cells[src]
+				if v, ok := f.wallValue(c); ok {
+					f.spare[q][i] = 2*w5[q]*v - out
+					if c == HotWallCell {
+						f.hotFlux += f.spare[q][i] - out
+					}
+				} else if c.Solid() {
+					f.spare[q][i] = out
+				} else {
+					f.spare[q][i] = f.g[q][src]
+				}
+			}
+		}
+	}
+	f.g, f.spare = f.spare, f.g
+}
+
+// EnableScalar turns on a passive scalar, such as dye, with the given
+// diffusivity in lattice units. It starts at zero everywhere.
+func (s *Solver) EnableScalar(diffusivity float32) {
+	inlet, source := float32(0), float32(1)
+	if s.scalar != nil {
+		inlet, source = s.scalar.inlet, s.scalar.source
+	}
+	s.scalar = newADField(s.numElements, diffusivity)
+	s.scalar.inlet, s.scalar.source = inlet, source
+	s.resetADField(s.scalar, 0)
+}
+
+// DisableScalar turns the passive scalar off
+func (s *Solver) DisableScalar() {
+	s.scalar = nil
+}
+
+// ScalarEnabled reports whether the passive scalar is solved
+func (s *Solver) ScalarEnabled() bool {
+	return s.scalar != nil
+}
+
+// SetScalarDiffusivity sets the diffusivity of the passive scalar, it has
+// no effect while the scalar is disabled
+func (s *Solver) SetScalarDiffusivity(d float32) {
+	if s.scalar != nil {
+		s.scalar.diffusivity = d
+	}
+}
+
+// SetScalarInlet sets the value of the scalar entering through the inlets,
+// 0 by default
+func (s *Solver) SetScalarInlet(c float32) {
+	if s.scalar != nil {
+		s.scalar.inlet = c
+	}
+}
+
+// SetScalarSource sets the value of the scalar held at the source sites,
+// 1 by default
+func (s *Solver) SetScalarSource(c float32) {
+	if s.scalar != nil {
+		s.scalar.source = c
+	}
+}
+
+// Scalar returns the passive scalar field, nil if it is disabled
+func (s *Solver) Scalar() []float32 {
+	if s.scalar == nil {
+		return nil
+	}
+	return s.scalar.c
+}
+
+// Advance the scalar fields by one time step
+func (s *Solver) stepScalars() {
+	if s.scalar != nil {
+		s.collideADField(s.scalar)
+		s.streamADField(s.scalar)
+	}
+	if s.thermal != nil {
+		s.updateThermal()
+		s.collideADField(s.thermal)
+		s.streamADField(s.thermal)
+	}
+}
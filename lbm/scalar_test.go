@@ -0,0 +1,71 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Periodic box of fluid at the velocity vel along x with a Gaussian spot
+// of dye of variance var0 in its middle
+func dyeSpot(vel, diffusivity, var0 float32) *Solver {
+	const n = 64
+	s := CreateSolver(n, n, vel, 0.1)
+	s.SetPeriodicBoundaries()
+	s.InitalizeLattice(n, n, vel, 0.1, EMPTY)
+	s.EnableScalar(diffusivity)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			r2 := float64((x-n/2)*(x-n/2) + (y-n/2)*(y-n/2))
+			i := s.Index(x, y)
+			s.scalar.setEquilibrium(i, float32(math.Exp(-r2/(2*float64(var0)))), s.ux[i], s.uy[i])
+		}
+	}
+	return s
+}
+
+// Total scalar and its variance along y, summed from the populations so
+// that they are up to date after streaming
+func dyeMoments(s *Solver) (total, variance float64) {
+	var my, myy float64
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			var c float64
+			for q := 0; q < q5; q++ {
+				c += float64(s.scalar.g[q][s.Index(x, y)])
+			}
+			total += c
+			my += c * float64(y)
+			myy += c * float64(y*y)
+		}
+	}
+	my /= total
+	return total, myy/total - my*my
+}
+
+func TestScalarConservedInPeriodicBox(t *testing.T) {
+	for _, vel := range []float32{0, 0.05} {
+		s := dyeSpot(vel, 0.05, 4)
+		want, _ := dyeMoments(s)
+		for step := 0; step < 500; step++ {
+			s.Step()
+		}
+		if got, _ := dyeMoments(s); math.Abs(got-want) > 1e-4*want {
+			t.Errorf("velocity %g: total scalar %g after 500 steps, want %g", vel, got, want)
+		}
+	}
+}
+
+func TestScalarSpotSpreadsDiffusively(t *testing.T) {
+	const var0, steps = 4, 400
+	for _, d := range []float32{0.02, 0.05, 0.1} {
+		s := dyeSpot(0, d, var0)
+		for step := 0; step < steps; step++ {
+			s.Step()
+		}
+		_, got := dyeMoments(s)
+		want := var0 + 2*float64(d)*steps
+		if math.Abs(got-want) > 0.02*(want-var0) {
+			t.Errorf("diffusivity %g: variance %g after %d steps, want %g", d, got, steps, want)
+		}
+	}
+}
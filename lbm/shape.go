@@ -0,0 +1,123 @@
+package lbm
+
+import "math"
+
+// Shape is the exact geometry of a barrier, used to place the wall between
+// lattice sites in the interpolated bounce-back
+type Shape interface {
+	// Contains reports whether the point (x, y) lies inside the shape
+	Contains(x, y float32) bool
+
+	// Intersect returns the fraction of the segment from (x0, y0) to
+	// (x1, y1) at which it first crosses the surface of the shape
+	Intersect(x0, y0, x1, y1 float32) (float32, bool)
+
+	// Bounds returns the bounding box of the shape
+	Bounds() (xmin, ymin, xmax, ymax float32)
+}
+
+// Circle is a circular barrier centered on (X, Y) with radius R
+type Circle struct {
+	X, Y, R float32
+}
+
+func (c Circle) Contains(x, y float32) bool {
+	dx, dy := x-c.X, y-c.Y
+	return dx*dx+dy*dy <= c.R*c.R
+}
+
+func (c Circle) Intersect(x0, y0, x1, y1 float32) (float32, bool) {
+	// solve |p0 + t*(p1-p0) - c|^2 = R^2 for the smallest t in [0, 1]
+	dx, dy := float64(x1-x0), float64(y1-y0)
+	fx, fy := float64(x0-c.X), float64(y0-c.Y)
+	a := dx*dx + dy*dy
+	b := 2 * (fx*dx + fy*dy)
+	cc := fx*fx + fy*fy - float64(c.R)*float64(c.R)
+	disc := b*b - 4*a*cc
+	if a == 0 || disc < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(disc)
+	for _, t := range []float64{(-b - sq) / (2 * a), (-b + sq) / (2 * a)} {
+		if t >= 0 && t <= 1 {
+			return float32(t), true
+		}
+	}
+	return 0, false
+}
+
+func (c Circle) Bounds() (float32, float32, float32, float32) {
+	return c.X - c.R, c.Y - c.R, c.X + c.R, c.Y + c.R
+}
+
+// Polygon is a closed polygonal barrier with vertices (X[i], Y[i])
+type Polygon struct {
+	X, Y []float32
+}
+
+func (p Polygon) Contains(x, y float32) bool {
+	// even-odd rule
+	inside := false
+	n := len(p.X)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if (p.Y[i] > y) != (p.Y[j] > y) &&
+			x < (p.X[j]-p.X[i])*(y-p.Y[i])/(p.Y[j]-p.Y[i])+p.X[i] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (p Polygon) Intersect(x0, y0, x1, y1 float32) (float32, bool) {
+	best, found := float32(2), false
+	n := len(p.X)
+	dx, dy := x1-x0, y1-y0
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		ex, ey := p.X[i]-p.X[j], p.Y[i]-p.Y[j]
+		den := dx*ey - dy*ex
+		if den == 0 {
+			continue
+		}
+		wx, wy := p.X[j]-x0, p.Y[j]-y0
+		t := (wx*ey - wy*ex) / den // along the segment
+		u := (wx*dy - wy*dx) / den // along the polygon edge
+		if t >= 0 && t <= 1 && u >= 0 && u <= 1 && t < best {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+func (p Polygon) Bounds() (float32, float32, float32, float32) {
+	xmin, ymin := float32(math.MaxFloat32), float32(math.MaxFloat32)
+	xmax, ymax := -xmin, -ymin
+	for i := range p.X {
+		xmin = min(xmin, p.X[i])
+		xmax = max(xmax, p.X[i])
+		ymin = min(ymin, p.Y[i])
+		ymax = max(ymax, p.Y[i])
+	}
+	return xmin, ymin, xmax, ymax
+}
+
+// AddShape adds a body whose barriers are the sites inside the shape. The
+// shape is kept, so the walls are placed at the exact surface by the
+// interpolated bounce-back. The body rotates about the center of the
+// bounding box of the shape.
+func (s *Solver) AddShape(shape Shape) int {
+	xmin, ymin, xmax, ymax := shape.Bounds()
+	id := s.AddBody(Body{
+		CenterX: (xmin + xmax) / 2,
+		CenterY: (ymin + ymax) / 2,
+		Size:    ymax - ymin,
+		Shape:   shape,
+	})
+	for y := int(math.Floor(float64(ymin))); y <= int(math.Ceil(float64(ymax))); y++ {
+		for x := int(math.Floor(float64(xmin))); x <= int(math.Ceil(float64(xmax))); x++ {
+			if shape.Contains(float32(x), float32(y)) {
+				s.SetBodyBarrier(x, y, id)
+			}
+		}
+	}
+	return id
+}
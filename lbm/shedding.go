@@ -0,0 +1,100 @@
+package lbm
+
+import "math"
+
+// LiftHistoryLength is the number of time steps of lift kept for every body
+const LiftHistoryLength = 8192
+
+// Append the lift of every body to its time series
+func (s *Solver) recordLift() {
+	if len(s.lift) != len(s.bodies) {
+		s.lift = make([][]float32, len(s.bodies))
+		for id := range s.lift {
+			s.lift[id] = make([]float32, LiftHistoryLength)
+		}
+		s.liftPos, s.liftLen = 0, 0
+	}
+	for id := range s.lift {
+		s.lift[id][s.liftPos] = s.forces[id].Fy
+	}
+	s.liftPos = (s.liftPos + 1) % LiftHistoryLength
+	if s.liftLen < LiftHistoryLength {
+		s.liftLen++
+	}
+}
+
+// LiftHistory returns the lift on the body with the given ID over the last
+// time steps, oldest first
+func (s *Solver) LiftHistory(id int) []float32 {
+	if id >= len(s.lift) {
+		return nil
+	}
+	h := make([]float32, s.liftLen)
+	start := s.liftPos - s.liftLen
+	if start < 0 {
+		start += LiftHistoryLength
+	}
+	for i := range h {
+		h[i] = s.lift[id][(start+i)%LiftHistoryLength]
+	}
+	return h
+}
+
+// SheddingFrequency returns the frequency, per time step, at which the lift
+// on the body with the given ID oscillates. It is found from the upward
+// crossings of the mean lift and is only reported once the history holds two
+// full periods of an oscillation that is not just noise.
+func (s *Solver) SheddingFrequency(id int) (float32, bool) {
+	h := s.LiftHistory(id)
+	if len(h) < 3 {
+		return 0, false
+	}
+	var mean, amp float64
+	for _, v := range h {
+		mean += float64(v)
+	}
+	mean /= float64(len(h))
+	for _, v := range h {
+		amp = math.Max(amp, math.Abs(float64(v)-mean))
+	}
+	// ignore lift coefficients below 1e-3
+	b := &s.bodies[id]
+	if amp < 1e-3*0.5*float64(s.flowVel*s.flowVel*b.Size) || amp == 0 {
+		return 0, false
+	}
+
+	// upward crossings, armed once the lift has dropped well below the mean
+	var first, last float64
+	crossings := 0
+	armed := false
+	for i := 1; i < len(h); i++ {
+		prev, cur := float64(h[i-1])-mean, float64(h[i])-mean
+		if cur < -0.25*amp {
+			armed = true
+		}
+		if armed && prev < 0 && cur >= 0 {
+			t := float64(i-1) + prev/(prev-cur)
+			if crossings == 0 {
+				first = t
+			}
+			last = t
+			crossings++
+			armed = false
+		}
+	}
+	if crossings < 3 {
+		return 0, false
+	}
+	return float32(float64(crossings-1) / (last - first)), true
+}
+
+// StrouhalNumber returns the Strouhal number f D / U of the vortex shedding
+// behind the body with the given ID, with D the size of the body and U the
+// inflow velocity
+func (s *Solver) StrouhalNumber(id int) (float32, bool) {
+	f, ok := s.SheddingFrequency(id)
+	if !ok || s.flowVel == 0 {
+		return 0, false
+	}
+	return f * s.bodies[id].Size / s.flowVel, true
+}
@@ -0,0 +1,47 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSheddingFrequencyOfASinusoidalLift(t *testing.T) {
+	for _, period := range []float64{40, 137.5, 600} {
+		s := CreateSolver(64, 32, 0.1, 0.03)
+		s.InitalizeLattice(64, 32, 0.1, 0.03, EMPTY)
+		s.AddBody(Body{Size: 10})
+		s.forces = make([]BodyForce, 1)
+		for step := 0; step < LiftHistoryLength; step++ {
+			s.forces[0].Fy = float32(0.01 * math.Sin(2*math.Pi*float64(step)/period))
+			s.recordLift()
+		}
+		f, ok := s.SheddingFrequency(0)
+		if !ok || math.Abs(float64(f)*period-1) > 1e-3 {
+			t.Errorf("period %g: shedding frequency %g (%v), want %g", period, f, ok, 1/period)
+		}
+	}
+}
+
+func TestCylinderStrouhalNumber(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the vortex street takes thousands of steps to develop")
+	}
+	// A cylinder of diameter 13 at Re 100, just off the centerline so the
+	// street starts sooner. The unbounded Strouhal number is 0.164, the
+	// blockage of the channel raises it a little.
+	const vel, visc = 0.1, 0.013
+	s := CreateSolver(128, 96, vel, visc)
+	s.InitalizeLattice(128, 96, vel, visc, EMPTY)
+	s.AddShape(Circle{X: 32, Y: 48.3, R: 6.5})
+	for step := 0; step < 5000; step++ {
+		s.Step()
+		// leave the start up out of the lift history
+		if step == 2500 {
+			s.liftLen = 0
+		}
+	}
+	st, ok := s.StrouhalNumber(0)
+	if !ok || st < 0.16 || st > 0.2 {
+		t.Errorf("Strouhal number %g (%v) at Re 100, want 0.16 to 0.2", st, ok)
+	}
+}
@@ -0,0 +1,31 @@
+package lbm
+
+import "math"
+
+// SmagorinskyOmega returns the relaxation rate of a site with the eddy
+// viscosity of the Smagorinsky subgrid model added to the molecular
+// viscosity. The strain rate is taken from the non-equilibrium part of the
+// populations f, so no finite differences are needed. omega is the
+// relaxation rate of the molecular viscosity and cs the Smagorinsky constant,
+// 0 returns omega unchanged.
+func SmagorinskyOmega(f *[Q]float32, rho, ux, uy, omega, cs float32) float32 {
+	if cs == 0 {
+		return omega
+	}
+	var feq [Q]float32
+	Equilibrium(&feq, rho, ux, uy)
+
+	// Non-equilibrium momentum flux
+	var pxx, pyy, pxy float32
+	for q := 0; q < Q; q++ {
+		fneq := f[q] - feq[q]
+		pxx += Cx[q] * Cx[q] * fneq
+		pyy += Cy[q] * Cy[q] * fneq
+		pxy += Cx[q] * Cy[q] * fneq
+	}
+	pi := float32(math.Sqrt(float64(2 * (pxx*pxx + pyy*pyy + 2*pxy*pxy))))
+
+	tau0 := 1.0 / omega
+	tau := 0.5 * (tau0 + float32(math.Sqrt(float64(tau0*tau0+18*cs*cs*pi/rho))))
+	return 1.0 / tau
+}
@@ -0,0 +1,50 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSmagorinskyWithoutConstantKeepsOmega(t *testing.T) {
+	for _, visc := range []float32{0.005, 0.03, 0.2} {
+		var f [Q]float32
+		Equilibrium(&f, 1.01, 0.05, -0.02)
+		f[DNE] += 0.003
+		f[DS] -= 0.002
+		rho, ux, uy := Moments(&f)
+		if got := SmagorinskyOmega(&f, rho, ux, uy, Omega(visc), 0); got != Omega(visc) {
+			t.Errorf("viscosity %g: omega %g with Cs 0, want %g", visc, got, Omega(visc))
+		}
+	}
+}
+
+func TestSmagorinskyEddyViscosityInCouetteFlow(t *testing.T) {
+	const xdim, ydim, lid, visc = 4, 34, 0.2, 0.005
+	const h = ydim - 2
+	for _, cs := range []float32{0.1, 0.3, 0.5} {
+		s := CreateSolver(xdim, ydim, lid, visc)
+		s.SetBoundary(Left, Periodic)
+		s.SetBoundary(Right, Periodic)
+		s.SetBoundary(Bottom, NoSlipWall)
+		s.SetBoundary(Top, MovingWall)
+		s.SetBoundaryVelocity(Top, lid)
+		s.InitalizeLattice(xdim, ydim, lid, visc, EMPTY)
+		s.SetSmagorinskyConstant(cs)
+		// uniform shear between the walls, half way between the edge sites
+		// and the fluid
+		for y := 0; y < ydim; y++ {
+			for x := 0; x < xdim; x++ {
+				s.SetEquilibrium(x, y, lid*(float32(y)-0.5)/h, 0, 1)
+			}
+		}
+		for step := 0; step < 200; step++ {
+			s.Step()
+		}
+		// the strain rate magnitude sqrt(2 S:S) of simple shear is du/dy
+		want := visc + cs*cs*lid/h
+		got := s.EffectiveViscosity()[s.Index(1, ydim/2)]
+		if math.Abs(float64(got-want)) > 0.02*float64(want-visc) {
+			t.Errorf("Cs %g: viscosity %g, want %g", cs, got, want)
+		}
+	}
+}
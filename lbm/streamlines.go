@@ -0,0 +1,87 @@
+package lbm
+
+import (
+	"image"
+	image_color "image/color"
+	"math"
+)
+
+// Distance between the points of a streamline, in sites
+const streamlineStep = 0.5
+
+// SetStreamlines draws streamlines over the plot, seeded every spacing
+// sites, in the given color. A spacing of 0 turns them off.
+func (s *Solver) SetStreamlines(spacing int, c image_color.RGBA) {
+	s.streamlineSpacing = spacing
+	s.streamlineColor = c
+}
+
+// Streamlines returns the spacing and the color of the streamlines
+func (s *Solver) Streamlines() (int, image_color.RGBA) {
+	return s.streamlineSpacing, s.streamlineColor
+}
+
+// Streamline integrates the streamline through the point (x, y) in both
+// directions with fourth order Runge-Kutta steps. It stops at barriers, at
+// the edges of the domain and where the flow is at rest. The points are
+// returned in the direction of the flow.
+func (s *Solver) Streamline(x, y float32) (xs, ys []float32) {
+	bx, by := s.traceStreamline(x, y, -1)
+	fx, fy := s.traceStreamline(x, y, 1)
+	for i := len(bx) - 1; i > 0; i-- {
+		xs = append(xs, bx[i])
+		ys = append(ys, by[i])
+	}
+	return append(xs, fx...), append(ys, fy...)
+}
+
+// Unit vector along the flow at (x, y), false where the flow is at rest
+func (s *Solver) flowDirection(x, y, dir float32) (float32, float32, bool) {
+	ux, uy := s.VelocityAt(x, y)
+	speed := float32(math.Sqrt(float64(ux*ux + uy*uy)))
+	if speed < 1e-6 {
+		return 0, 0, false
+	}
+	return dir * ux / speed, dir * uy / speed, true
+}
+
+// Follow the flow from (x, y), downstream for dir 1 and upstream for dir -1
+func (s *Solver) traceStreamline(x, y, dir float32) (xs, ys []float32) {
+	h := float32(streamlineStep)
+	maxPoints := int(2 * float32(s.xdim+s.ydim) / h)
+	xmax, ymax := float32(s.xdim-1), float32(s.ydim-1)
+	for n := 0; n < maxPoints; n++ {
+		if x < 0 || x > xmax || y < 0 || y > ymax || s.cells[s.nearestSite(x, y)].Solid() {
+			break
+		}
+		xs = append(xs, x)
+		ys = append(ys, y)
+		k1x, k1y, ok1 := s.flowDirection(x, y, dir)
+		k2x, k2y, ok2 := s.flowDirection(x+h/2*k1x, y+h/2*k1y, dir)
+		k3x, k3y, ok3 := s.flowDirection(x+h/2*k2x, y+h/2*k2y, dir)
+		k4x, k4y, ok4 := s.flowDirection(x+h*k3x, y+h*k3y, dir)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			break
+		}
+		x += h / 6 * (k1x + 2*k2x + 2*k3x + k4x)
+		y += h / 6 * (k1y + 2*k2y + 2*k3y + k4y)
+	}
+	return
+}
+
+// Draw the streamlines through a grid of seed points
+func (s *Solver) drawStreamlines(rgba *image.RGBA) {
+	spacing := s.streamlineSpacing
+	if spacing < 1 {
+		return
+	}
+	for y := spacing / 2; y < s.ydim; y += spacing {
+		for x := spacing / 2; x < s.xdim; x += spacing {
+			xs, ys := s.Streamline(float32(x), float32(y))
+			for i := range xs {
+				p := s.nearestSite(xs[i], ys[i])
+				rgba.SetRGBA(p%s.xdim, p/s.xdim, s.streamlineColor)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package lbm
+
+import "math"
+
+// Temperatures of the hot and cold walls. Buoyancy is measured from the
+// mean of the two, so the temperature difference driving convection is 1.
+const (
+	hotTemperature  = 1
+	coldTemperature = 0
+)
+
+// EnableThermal solves a temperature field coupled to the flow. The fluid
+// rises where it is warmer than the mean of the hot and cold walls, through
+// a Boussinesq buoyancy force along +y. The Rayleigh and Prandtl numbers set
+// the buoyancy and the thermal diffusivity from the viscosity of the flow,
+// with the height of the domain as the length scale. The temperature starts
+// at the mean with a small perturbation along x that sets off the convection
+// cells.
+func (s *Solver) EnableThermal(rayleigh, prandtl float32) {
+	s.rayleigh = rayleigh
+	s.prandtl = prandtl
+	s.thermal = newADField(s.numElements, 0)
+	s.thermal.thermal = true
+	s.updateThermal()
+	s.resetTemperature()
+}
+
+// DisableThermal turns the temperature field and the buoyancy off
+func (s *Solver) DisableThermal() {
+	s.thermal = nil
+}
+
+// ThermalEnabled reports whether the temperature field is solved
+func (s *Solver) ThermalEnabled() bool {
+	return s.thermal != nil
+}
+
+// SetRayleighNumber sets the Rayleigh number g beta dT L^3 / (nu kappa)
+func (s *Solver) SetRayleighNumber(ra float32) {
+	s.rayleigh = ra
+}
+
+func (s *Solver) RayleighNumber() float32 {
+	return s.rayleigh
+}
+
+// SetPrandtlNumber sets the Prandtl number nu / kappa
+func (s *Solver) SetPrandtlNumber(pr float32) {
+	s.prandtl = pr
+}
+
+func (s *Solver) PrandtlNumber() float32 {
+	return s.prandtl
+}
+
+// Temperature returns the temperature field, nil if it is disabled
+func (s *Solver) Temperature() []float32 {
+	if s.thermal == nil {
+		return nil
+	}
+	return s.thermal.c
+}
+
+// Length the Rayleigh and Nusselt numbers are based on, the height of the
+// fluid between the bottom and top edges
+func (s *Solver) thermalLength() float32 {
+	return float32(s.ydim - 2)
+}
+
+// NusseltNumber returns the mean Nusselt number q L / (kappa dT) of the hot
+// walls over the last time step, with q the heat flux through their surface
+// and L the height of the domain. The surface of a body is the perimeter of
+// its shape, painted bodies and the edges of the domain count the faces of
+// their sites. Pure conduction between hot and cold edges gives 1.
+func (s *Solver) NusseltNumber() float32 {
+	if s.thermal == nil || s.thermal.diffusivity == 0 {
+		return 0
+	}
+	perimeter := make([]float32, len(s.bodies))
+	for id, b := range s.bodies {
+		perimeter[id] = shapePerimeter(b.Shape)
+	}
+	var area float32
+	shaped := make([]bool, len(s.bodies))
+	x0, x1 := s.xRange()
+	y0, y1 := s.yRange()
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if s.cells[x+y*s.xdim].Solid() {
+				continue
+			}
+			for q := 1; q < q5; q++ {
+				n := (x+cx5[q]+s.xdim)%s.xdim + (y+cy5[q]+s.ydim)%s.ydim*s.xdim
+				if s.cells[n] != HotWallCell {
+					continue
+				}
+				if id := s.cellBody[n]; id >= 0 && perimeter[id] > 0 {
+					shaped[id] = true
+				} else {
+					area++
+				}
+			}
+		}
+	}
+	for id, ok := range shaped {
+		if ok {
+			area += perimeter[id]
+		}
+	}
+	if area == 0 {
+		return 0
+	}
+	dT := float32(hotTemperature - coldTemperature)
+	return s.thermal.hotFlux / area * s.thermalLength() / (s.thermal.diffusivity * dT)
+}
+
+// Perimeter of a shape, 0 if it is unknown
+func shapePerimeter(shape Shape) float32 {
+	switch sh := shape.(type) {
+	case Circle:
+		return 2 * math.Pi * sh.R
+	case Polygon:
+		var p float64
+		for i := range sh.X {
+			j := (i + 1) % len(sh.X)
+			p += math.Hypot(float64(sh.X[j]-sh.X[i]), float64(sh.Y[j]-sh.Y[i]))
+		}
+		return float32(p)
+	}
+	return 0
+}
+
+// SetBenardBoundaries sets up a Rayleigh-Bénard cell, a layer of fluid
+// between a hot bottom wall and a cold top wall that wraps around along x
+func (s *Solver) SetBenardBoundaries() {
+	s.SetBoundary(Left, Periodic)
+	s.SetBoundary(Right, Periodic)
+	s.SetBoundary(Bottom, HotWall)
+	s.SetBoundary(Top, ColdWall)
+}
+
+// SetEnclosureBoundaries closes the domain with cold walls
+func (s *Solver) SetEnclosureBoundaries() {
+	for edge := 0; edge < NumEdges; edge++ {
+		s.SetBoundary(edge, ColdWall)
+	}
+}
+
+// Derive the thermal diffusivity and the buoyancy per unit temperature from
+// the Rayleigh and Prandtl numbers and the current viscosity
+func (s *Solver) updateThermal() {
+	if s.prandtl <= 0 {
+		return
+	}
+	kappa := s.flowVisc / s.prandtl
+	l := s.thermalLength()
+	dT := float32(hotTemperature - coldTemperature)
+	s.thermal.diffusivity = kappa
+	s.buoyancy = s.rayleigh * s.flowVisc * kappa / (dT * l * l * l)
+}
+
+// Fill the temperature field with the mean temperature and a perturbation
+// of one wavelength across the domain
+func (s *Solver) resetTemperature() {
+	mean := float32(hotTemperature+coldTemperature) / 2
+	for y := 0; y < s.ydim; y++ {
+		for x := 0; x < s.xdim; x++ {
+			i := x + y*s.xdim
+			t := mean + 1e-3*float32(math.Sin(2*math.Pi*float64(x)/float64(s.xdim)))
+			s.thermal.setEquilibrium(i, t, s.ux[i], s.uy[i])
+		}
+	}
+}
+
+// Buoyancy force along y on the fluid at site i with density rho
+func (s *Solver) buoyancyForce(i int, rho float32) float32 {
+	if s.thermal == nil {
+		return 0
+	}
+	var t float32
+	for q := 0; q < q5; q++ {
+		t += s.thermal.g[q][i]
+	}
+	return rho * s.buoyancy * (t - float32(hotTemperature+coldTemperature)/2)
+}
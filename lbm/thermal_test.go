@@ -0,0 +1,32 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRayleighBenardNusseltNumber(t *testing.T) {
+	tests := []struct {
+		ra   float32
+		want float32
+	}{
+		// below the critical Rayleigh number of 1708 heat is only conducted
+		{1000, 1},
+		// Clever and Busse (1974), rolls of wavelength 2 at Pr 0.71
+		{5000, 2.116},
+		{10000, 2.661},
+	}
+	const visc = 0.03
+	for _, tt := range tests {
+		// one wavelength across a layer 20 sites deep
+		s := CreateSolver(42, 22, 0, visc)
+		s.InitalizeLattice(42, 22, 0, visc, BENARD)
+		s.EnableThermal(tt.ra, 0.71)
+		for step := 0; step < 8000; step++ {
+			s.Step()
+		}
+		if got := s.NusseltNumber(); math.Abs(float64(got-tt.want)) > 0.03*float64(tt.want) {
+			t.Errorf("Ra %g: Nusselt number %g, want %g", tt.ra, got, tt.want)
+		}
+	}
+}
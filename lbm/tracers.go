@@ -0,0 +1,203 @@
+package lbm
+
+import (
+	"image"
+	image_color "image/color"
+	"math"
+)
+
+// Tracer seeding modes
+const (
+	TracersOff   = iota
+	TracersGrid  // on a regular grid, recycled to their seed
+	TracersInlet // released in columns from the left edge
+	NumTracerModes
+)
+
+// Tracer is a particle carried by the flow
+type Tracer struct {
+	X, Y   float32
+	Vx, Vy float32
+
+	// where the tracer is recycled to once it leaves the domain
+	seedX, seedY float32
+}
+
+// SeedTracers replaces the tracers with new ones every spacing sites over
+// the fluid, or along the left edge for TracersInlet. TracersOff removes
+// them all.
+func (s *Solver) SeedTracers(mode, spacing int) {
+	s.tracers = s.tracers[:0]
+	s.tracerMode = mode
+	s.tracerSpacing = spacing
+	if mode == TracersOff || spacing < 1 {
+		return
+	}
+	if mode == TracersInlet {
+		s.releaseInletTracers()
+		return
+	}
+	for y := spacing / 2; y < s.ydim; y += spacing {
+		for x := spacing / 2; x < s.xdim; x += spacing {
+			if s.cells[x+y*s.xdim] != FluidCell {
+				continue
+			}
+			t := Tracer{X: float32(x), Y: float32(y), seedX: float32(x), seedY: float32(y)}
+			t.Vx, t.Vy = s.VelocityAt(t.X, t.Y)
+			s.tracers = append(s.tracers, t)
+		}
+	}
+}
+
+// Release a column of tracers every spacing sites along the left edge, each
+// on the first fluid site of its row, and time the release of the next
+// column for when this one has moved spacing sites downstream
+func (s *Solver) releaseInletTracers() {
+	x0, x1 := s.xRange()
+	for y := s.tracerSpacing / 2; y < s.ydim; y += s.tracerSpacing {
+		for x := x0; x <= x1; x++ {
+			if c := s.cells[x+y*s.xdim]; c.Solid() {
+				continue
+			} else if c != FluidCell {
+				break
+			}
+			t := Tracer{X: float32(x), Y: float32(y), seedX: float32(x), seedY: float32(y)}
+			t.Vx, t.Vy = s.VelocityAt(t.X, t.Y)
+			s.tracers = append(s.tracers, t)
+			break
+		}
+	}
+	s.tracerRelease = 0
+	if s.flowVel > 0 {
+		s.tracerRelease = max(int(float32(s.tracerSpacing)/s.flowVel+0.5), 1)
+	}
+}
+
+// Tracers returns the tracers
+func (s *Solver) Tracers() []Tracer {
+	return s.tracers
+}
+
+// TracerMode returns the seeding mode of the tracers
+func (s *Solver) TracerMode() int {
+	return s.tracerMode
+}
+
+// SetTracerStokes gives the tracers inertia. Their velocity relaxes
+// exponentially towards the flow over a response time of stokes L/U, with
+// L the barrier size (or the channel height without barriers) and U the
+// inflow velocity. A Stokes number of 0 makes the tracers follow the flow
+// exactly.
+func (s *Solver) SetTracerStokes(stokes float32) {
+	s.tracerStokes = stokes
+}
+
+func (s *Solver) TracerStokes() float32 {
+	return s.tracerStokes
+}
+
+// VelocityAt returns the flow velocity at the point (x, y), interpolated
+// bilinearly between the four surrounding sites. Solid sites count as being
+// at rest.
+func (s *Solver) VelocityAt(x, y float32) (float32, float32) {
+	x = min(max(x, 0), float32(s.xdim-1))
+	y = min(max(y, 0), float32(s.ydim-1))
+	x0, y0 := min(int(x), s.xdim-2), min(int(y), s.ydim-2)
+	fx, fy := x-float32(x0), y-float32(y0)
+	var ux, uy float32
+	for j := 0; j <= 1; j++ {
+		for i := 0; i <= 1; i++ {
+			idx := x0 + i + (y0+j)*s.xdim
+			if s.cells[idx].Solid() {
+				continue
+			}
+			w := (1 - fx + float32(i)*(2*fx-1)) * (1 - fy + float32(j)*(2*fy-1))
+			ux += w * s.ux[idx]
+			uy += w * s.uy[idx]
+		}
+	}
+	return ux, uy
+}
+
+// Move the tracers with the flow over one time step, tracers that leave the
+// domain or hit a barrier are recycled to their seed, or dropped when they
+// are released from the inlet
+func (s *Solver) advectTracers() {
+	if s.tracerMode == TracersInlet && s.tracerRelease > 0 {
+		s.tracerRelease--
+		if s.tracerRelease == 0 {
+			s.releaseInletTracers()
+		}
+	}
+	if len(s.tracers) == 0 {
+		return
+	}
+	tau := float32(0)
+	if s.tracerStokes > 0 && s.flowVel > 0 {
+		l := float32(s.BarrierSize())
+		if l == 0 {
+			l = float32(s.ydim - 2)
+		}
+		tau = s.tracerStokes * l / s.flowVel
+	}
+	periodicX, periodicY := s.PeriodicX(), s.PeriodicY()
+	// fraction of the slip velocity lost over a step
+	relax := float32(1)
+	if tau > 0 {
+		relax = float32(1 - math.Exp(-1/float64(tau)))
+	}
+	xmax, ymax := float32(s.xdim-1), float32(s.ydim-1)
+	kept := s.tracers[:0]
+	for i := range s.tracers {
+		t := &s.tracers[i]
+		ux, uy := s.VelocityAt(t.X, t.Y)
+		t.Vx += relax * (ux - t.Vx)
+		t.Vy += relax * (uy - t.Vy)
+		t.X += t.Vx
+		t.Y += t.Vy
+		if periodicX {
+			t.X = float32(math.Mod(float64(t.X)+float64(s.xdim), float64(s.xdim)))
+		}
+		if periodicY {
+			t.Y = float32(math.Mod(float64(t.Y)+float64(s.ydim), float64(s.ydim)))
+		}
+		outside := (!periodicX && (t.X < 0 || t.X > xmax)) || (!periodicY && (t.Y < 0 || t.Y > ymax))
+		if outside || s.cells[s.nearestSite(t.X, t.Y)].Solid() {
+			if s.tracerMode == TracersInlet {
+				continue
+			}
+			t.X, t.Y = t.seedX, t.seedY
+			t.Vx, t.Vy = s.VelocityAt(t.X, t.Y)
+		}
+		kept = append(kept, *t)
+	}
+	s.tracers = kept
+}
+
+// Index of the site closest to the point (x, y) inside the grid, points
+// past the last site round to the first one only on periodic axes
+func (s *Solver) nearestSite(x, y float32) int {
+	i, j := int(x+0.5), int(y+0.5)
+	if i >= s.xdim {
+		i = s.xdim - 1
+		if s.PeriodicX() {
+			i = 0
+		}
+	}
+	if j >= s.ydim {
+		j = s.ydim - 1
+		if s.PeriodicY() {
+			j = 0
+		}
+	}
+	return i + j*s.xdim
+}
+
+// Draw every tracer as a dark pixel
+func (s *Solver) drawTracers(rgba *image.RGBA) {
+	c := image_color.RGBA{30, 30, 30, 255}
+	for _, t := range s.tracers {
+		i := s.nearestSite(t.X, t.Y)
+		rgba.SetRGBA(i%s.xdim, i/s.xdim, c)
+	}
+}
@@ -0,0 +1,64 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInletTracersStartAtTheLeftEdge(t *testing.T) {
+	s := CreateSolver(64, 32, 0.1, 0.03)
+	s.InitalizeLattice(64, 32, 0.1, 0.03, LINE)
+	s.SeedTracers(TracersInlet, 4)
+	if len(s.Tracers()) != 8 {
+		t.Fatalf("released %d tracers, want one every 4 rows", len(s.Tracers()))
+	}
+	for _, tr := range s.Tracers() {
+		if tr.X != 1 {
+			t.Errorf("tracer released at x = %g, want 1", tr.X)
+		}
+	}
+	// the first column moves 4 sites in 40 steps at the flow velocity, when
+	// the second one is released
+	for step := 0; step < 40; step++ {
+		s.Step()
+	}
+	if len(s.Tracers()) != 16 {
+		t.Errorf("%d tracers after 40 steps, want two columns", len(s.Tracers()))
+	}
+}
+
+func TestTracerStokesResponse(t *testing.T) {
+	const vel = 0.1
+	tests := []struct {
+		tau  float32 // response time in steps
+		want float32 // velocity after one step from rest
+	}{
+		{0, vel},
+		{0.25, vel * (1 - float32(math.Exp(-4)))},
+		{1, vel * (1 - float32(math.Exp(-1)))},
+		{10, vel * (1 - float32(math.Exp(-0.1)))},
+	}
+	for _, tt := range tests {
+		s := CreateSolver(64, 32, vel, 0.03)
+		s.InitalizeLattice(64, 32, vel, 0.03, EMPTY)
+		// without barriers the length scale is the channel height
+		s.SetTracerStokes(tt.tau * vel / 30)
+		s.tracers = []Tracer{{X: 20, Y: 16, seedX: 20, seedY: 16}}
+		s.advectTracers()
+		if got := s.tracers[0].Vx; math.Abs(float64(got-tt.want)) > 1e-5 {
+			t.Errorf("tau %g: velocity %g after a step, want %g", tt.tau, got, tt.want)
+		}
+	}
+}
+
+func TestNearestSiteWrapsOnlyPeriodicAxes(t *testing.T) {
+	s := CreateSolver(16, 8, 0.1, 0.03)
+	s.InitalizeLattice(16, 8, 0.1, 0.03, EMPTY)
+	if got, want := s.nearestSite(15.7, 7.8), s.Index(15, 7); got != want {
+		t.Errorf("wind tunnel: site %d past the corner, want %d", got, want)
+	}
+	s.SetPeriodicBoundaries()
+	if got, want := s.nearestSite(15.7, 7.8), s.Index(0, 0); got != want {
+		t.Errorf("periodic: site %d past the corner, want %d", got, want)
+	}
+}
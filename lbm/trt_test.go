@@ -0,0 +1,45 @@
+package lbm
+
+import (
+	"math"
+	"testing"
+)
+
+// Run a channel between bounce-back walls at the bottom and the top, with
+// periodic ends and driven by the force density fx, to its steady state.
+// Returns the velocity profile across the channel and the analytic
+// Poiseuille profile with the walls half way between the edge sites and the
+// fluid.
+func poiseuille(t *testing.T, op CollisionOperator, visc, fx float32, steps int) (got, want []float32) {
+	t.Helper()
+	const xdim, ydim = 4, 18
+	s := CreateSolver(xdim, ydim, 0, visc)
+	s.SetBoundary(Left, Periodic)
+	s.SetBoundary(Right, Periodic)
+	s.SetBoundary(Bottom, NoSlipWall)
+	s.SetBoundary(Top, NoSlipWall)
+	s.InitalizeLattice(xdim, ydim, 0, visc, EMPTY)
+	s.SetCollisionOperator(op)
+	s.SetExternalForce(fx, 0)
+	for step := 0; step < steps; step++ {
+		s.Step()
+	}
+	h := float32(ydim - 2)
+	for y := 1; y < ydim-1; y++ {
+		d := float32(y) - 0.5
+		got = append(got, s.ux[s.Index(1, y)])
+		want = append(want, fx/(2*visc)*d*(h-d))
+	}
+	return got, want
+}
+
+func TestTRTMagicPutsWallsHalfWay(t *testing.T) {
+	for _, visc := range []float32{0.1, 0.3} {
+		got, want := poiseuille(t, &TRT{Magic: 3.0 / 16}, visc, 1e-5, 6000)
+		for i := range got {
+			if math.Abs(float64(got[i]-want[i])) > 0.005*float64(want[len(want)/2]) {
+				t.Errorf("viscosity %g: ux %g at row %d, want %g", visc, got[i], i+1, want[i])
+			}
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	_ "image/png"
 	"log"
 
+	"github.com/prasadchandan/go_lbm/lbm"
 	"github.com/prasadchandan/go_lbm/uiengine"
 
 	"golang.org/x/mobile/app"
@@ -57,7 +58,7 @@ var (
 
 	// Android specific hack
 	isGridInit bool
-	solver     *Solver
+	solver     *lbm.Solver
 )
 
 func main() {
@@ -241,8 +242,8 @@ func onStart(glctx gl.Context) {
 	fVelocity := float32(0.1)
 	fViscosity := float32(0.03)
 
-	solver = CreateSolver(gridX, gridY, fVelocity, fViscosity)
-	solver.InitalizeLattice(gridX, gridY, fVelocity, fViscosity, LINE)
+	solver = lbm.CreateSolver(gridX, gridY, fVelocity, fViscosity)
+	solver.InitalizeLattice(gridX, gridY, fVelocity, fViscosity, lbm.LINE)
 
 	buf = glctx.CreateBuffer()
 	glctx.BindBuffer(gl.ARRAY_BUFFER, buf)
@@ -263,7 +264,7 @@ func onStart(glctx gl.Context) {
 
 func resetSimulation(gX, gY int, fVelocity, fViscosity float32) {
 	// Assumes correct aspect ratio provided
-	solver.InitalizeLattice(gX, gY, fVelocity, fViscosity, LINE)
+	solver.InitalizeLattice(gX, gY, fVelocity, fViscosity, lbm.LINE)
 }
 
 func onStop(glctx gl.Context) {
@@ -289,7 +290,12 @@ func onPaint(glctx gl.Context, sz size.Event) {
 	if !props.PauseSimulation {
 		solver.SetFlowVelocity(props.Fvel)
 		solver.SetFlowViscosity(props.Fvis)
-		solver.Simulate(&props)
+		solver.SetSmagorinskyConstant(props.Smagorinsky)
+		solver.SetExternalForce(externalForces[props.Force], 0)
+		for id := 0; id < solver.NumBodies(); id++ {
+			solver.SetBodySpinRatio(id, props.Spin)
+		}
+		solver.Simulate(props.DragFluidCheck(solver))
 
 		var err error
 		texture, err = CreateSimTexture(glctx)
@@ -315,6 +321,22 @@ func onPaint(glctx gl.Context, sz size.Event) {
 		props.DebugWindow.Draw(ui)
 	}
 	props.Fps.SetText(ui, fmt.Sprint("FPS: ", strconv.FormatInt(int64(fpsSrc.GetFps()), 10)))
+	props.Reynolds.SetText(ui, fmt.Sprintf("Re: %.0f", solver.ReynoldsNumber()))
+	if solver.NumBodies() > 0 {
+		f := solver.BodyForce(0)
+		props.Forces.SetText(ui, fmt.Sprintf("Cd %.2f Cl %.2f", f.Cd, f.Cl))
+	} else if dp, ok := solver.PorousPressureDrop(); ok {
+		props.Forces.SetText(ui, fmt.Sprintf("dP %.4f", dp))
+	} else {
+		props.Forces.SetText(ui, "Cd -")
+	}
+	if solver.ThermalEnabled() {
+		props.Strouhal.SetText(ui, fmt.Sprintf("Nu %.2f", solver.NusseltNumber()))
+	} else if st, ok := solver.StrouhalNumber(0); ok {
+		props.Strouhal.SetText(ui, fmt.Sprintf("St %.3f", st))
+	} else {
+		props.Strouhal.SetText(ui, "St -")
+	}
 
 	//fps.Draw(sz)
 }
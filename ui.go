@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	image_color "image/color"
+	"strings"
 
+	"github.com/prasadchandan/go_lbm/lbm"
 	"github.com/prasadchandan/go_lbm/uiengine"
 )
 
@@ -12,6 +14,22 @@ func getBarrierString(btype int) string {
 		return "Line"
 	} else if btype == 1 {
 		return "Circle"
+	} else if btype == 2 {
+		return "Cavity"
+	} else if btype == 3 {
+		return "Benard"
+	} else if btype == 4 {
+		return "Heated"
+	} else if btype == 5 {
+		return "Empty"
+	} else if btype == 6 {
+		return "Drop"
+	} else if btype == 7 {
+		return "Phases"
+	} else if btype == 8 {
+		return "Fingers"
+	} else if btype == 9 {
+		return "Porous"
 	} else {
 		return "Unknown"
 	}
@@ -29,10 +47,231 @@ func getPlotTypeString(ptype int) string {
 		return "Vmag"
 	case 4:
 		return "CurlV"
+	case 5:
+		return "Visc"
+	case 6:
+		return "Dye"
+	case 7:
+		return "Temp"
+	case 8:
+		return "Phase"
 	}
 	return "Unknown"
 }
 
+func getCollisionString(ctype int) string {
+	return strings.ToUpper(lbm.CollisionOperatorNames()[ctype])
+}
+
+func getEdgeString(edge int) string {
+	opt := []string{"Left", "Right", "Bottom", "Top"}
+	return opt[edge]
+}
+
+func getBoundaryString(btype lbm.BoundaryType) string {
+	name := btype.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Wind tunnel boundary conditions, used unless a lid-driven cavity or a
+// natural convection barrier is selected
+var defaultBoundaries = [lbm.NumEdges]lbm.BoundaryType{lbm.VelocityInlet, lbm.PressureOutlet, lbm.VelocityInlet, lbm.VelocityInlet}
+
+// Barriers that come with their own boundary conditions
+func ownBoundaries(btype int) bool {
+	return btype == lbm.CAVITY || btype == lbm.BENARD || btype == lbm.HEATED || multiphase(btype) || btype == lbm.FINGERS
+}
+
+// Barriers solved with the Shan-Chen multiphase model
+func multiphase(btype int) bool {
+	return btype == lbm.DROP || btype == lbm.PHASES
+}
+
+// Lowest viscosity the multiphase barriers are selected with, they turn
+// unstable at the viscosities used for the wind tunnel
+const multiphaseViscosity = 1.0 / 6.0
+
+// Select the barrier type, leaving the lid-driven cavity or the natural
+// convection barriers restores the wind tunnel boundaries
+func setBarrierType(pro *AppProperties, btype int) {
+	if ownBoundaries(pro.Barrier) && !ownBoundaries(btype) {
+		pro.Boundaries = defaultBoundaries
+	}
+	pro.Barrier = btype
+	if multiphase(btype) && pro.Fvis < multiphaseViscosity {
+		pro.Fvis = multiphaseViscosity
+	}
+}
+
+// Force densities pushing the fluid along +x, enough for a gravity-driven
+// channel flow between walls with periodic ends
+var externalForces = []float32{0, 1e-6, 2e-6, 5e-6}
+
+func getForceString(i int) string {
+	if externalForces[i] == 0 {
+		return "Off"
+	}
+	return fmt.Sprintf("%.0e", externalForces[i])
+}
+
+// Rayleigh and Prandtl numbers of the natural convection barriers
+var (
+	rayleighNumbers = []float32{1e3, 1e4, 1e5}
+	prandtlNumbers  = []float32{0.71, 1, 7}
+)
+
+func getRayleighString(i int) string {
+	return fmt.Sprintf("%.0e", rayleighNumbers[i])
+}
+
+func getPrandtlString(i int) string {
+	return fmt.Sprint(prandtlNumbers[i])
+}
+
+// Shan-Chen interaction strengths and contact angles, in degrees, of the
+// multiphase barriers. The angles stay in the range SetContactAngle
+// reproduces; -4.5 is kept for mixtures at low viscosities, but drops only
+// hold their angle from -5 on.
+var (
+	interactionStrengths = []float32{-4.5, -5, -5.5}
+	contactAngles        = []float32{60, 90, 120}
+)
+
+func getInteractionString(i int) string {
+	return fmt.Sprint(interactionStrengths[i])
+}
+
+func getContactAngleString(i int) string {
+	return fmt.Sprint(contactAngles[i])
+}
+
+// Viscosity of the displaced fluid over that of the injected one, and
+// surface tensions between them, for the fingering barrier
+var (
+	viscosityRatios = []float32{1, 3, 10}
+	surfaceTensions = []float32{0.001, 0.005, 0.01}
+)
+
+func getViscosityRatioString(i int) string {
+	return fmt.Sprint(viscosityRatios[i])
+}
+
+func getTensionString(i int) string {
+	return fmt.Sprint(surfaceTensions[i])
+}
+
+// Solid fractions of the porous block and of painted porous sites
+var solidFractions = []float32{0.01, 0.02, 0.05, 0.1, 0.2}
+
+func getSolidFractionString(i int) string {
+	return fmt.Sprint(solidFractions[i])
+}
+
+// Magic parameters of the TRT operator: 1/12 cancels the third order
+// advection error, 3/16 puts bounce-back walls exactly half way for
+// Poiseuille flow and 1/4 is the most stable
+var magicParameters = []float32{1.0 / 12, 1.0 / 6, 3.0 / 16, 1.0 / 4, 1.0 / 2}
+
+func getMagicString(i int) string {
+	return []string{"1/12", "1/6", "3/16", "1/4", "1/2"}[i]
+}
+
+// Relaxation rates of the MRT ghost moments, rates close to 2 damp the
+// least and 1 relaxes the ghost moments straight to equilibrium
+var ghostRates = []float32{1.0, 1.2, 1.5, 1.9}
+
+func getGhostRateString(i int) string {
+	return fmt.Sprint(ghostRates[i])
+}
+
+// Set the boundary condition of an edge, periodic conditions are kept
+// paired on opposite edges
+func setEdgeBoundary(pro *AppProperties, edge int, btype lbm.BoundaryType) {
+	opposite := lbm.OppositeEdge[edge]
+	if btype == lbm.Periodic || pro.Boundaries[edge] == lbm.Periodic {
+		pro.Boundaries[opposite] = btype
+	}
+	pro.Boundaries[edge] = btype
+}
+
+// Viscosity slider increment, finer steps at low viscosities
+func getViscStep(visc float32, up bool) float32 {
+	if (up && visc < 0.0095) || (!up && visc <= 0.0105) {
+		return 0.001
+	}
+	return 0.005
+}
+
+func getTouchModeString(mode int) string {
+	opt := []string{"Drag", "Probe", "Source", "Porous"}
+	return opt[mode]
+}
+
+func getTracerModeString(mode int) string {
+	opt := []string{"Off", "Grid", "Inlet"}
+	return opt[mode]
+}
+
+// Dye modes: off, released only from painted sources, and also carried in
+// through the inlets
+const (
+	dyeOff = iota
+	dyeSource
+	dyeInlet
+	numDyeModes
+)
+
+func getDyeString(mode int) string {
+	opt := []string{"Off", "Source", "Inlet"}
+	return opt[mode]
+}
+
+// Diffusivity of the dye in lattice units
+const dyeDiffusivity = 0.02
+
+// Pass the dye mode selected in the menu to the solver
+func setDye(pro *AppProperties) {
+	if pro.Dye == dyeOff {
+		solver.DisableScalar()
+		return
+	}
+	if !solver.ScalarEnabled() {
+		solver.EnableScalar(dyeDiffusivity)
+	}
+	inlet := float32(0)
+	if pro.Dye == dyeInlet {
+		inlet = 1
+	}
+	solver.SetScalarInlet(inlet)
+}
+
+// Streamline densities, as the sites between seed points
+var streamlineSpacings = []int{0, 16, 8}
+
+func getStreamlineString(density int) string {
+	opt := []string{"Off", "Sparse", "Dense"}
+	return opt[density]
+}
+
+// Streamline colors
+var streamlineColors = []image_color.RGBA{{255, 255, 255, 255}, {0, 0, 0, 255}, {255, 0, 255, 255}}
+
+func getStreamlineColorString(c int) string {
+	opt := []string{"White", "Black", "Magenta"}
+	return opt[c]
+}
+
+// Pass the streamline settings selected in the menu to the solver
+func setStreamlines(pro *AppProperties) {
+	solver.SetStreamlines(streamlineSpacings[pro.Streamlines], streamlineColors[pro.StreamlineColor])
+}
+
+// Top of the bottom bar in normalized device coordinates
+const bottomBarTop = -0.88
+
+// Sites between tracers when they are seeded
+const tracerSpacing = 4
+
 func getRenderTypeString(rtype int) string {
 	opt := []string{"NEA", "LIN", "BILIN", "TILIN"}
 	return opt[rtype]
@@ -60,7 +299,16 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 	props.YGrid = gridy
 	props.Fvel = 0.1
 	props.Fvis = 0.03
-	props.Barrier = LINE
+	props.Barrier = lbm.LINE
+	props.Rayleigh = 1
+	props.Interaction = 1
+	props.Wetting = 1
+	props.ViscosityRatio = 2
+	props.Tension = 1
+	props.Porosity = 2
+	props.Magic = 3
+	props.Ghost = 3
+	props.Boundaries = defaultBoundaries
 	props.RenderOpt = 1
 	props.PxPerSimSquare = props.Device.ScreenDim[uiengine.X] / props.YGrid
 
@@ -73,8 +321,30 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 	visSlider := props.Menu.AddSlider("Visc", props.Fvis)
 	plotSlider := props.Menu.AddSlider("Disp", props.Plot)
 	barrierSlider := props.Menu.AddSlider("B-Type", props.Barrier)
+	collisionSlider := props.Menu.AddSlider("Coll", getCollisionString(props.Collision))
+	magicSlider := props.Menu.AddSlider("Magic", getMagicString(props.Magic))
+	ghostSlider := props.Menu.AddSlider("Ghost", getGhostRateString(props.Ghost))
+	smagorinskySlider := props.Menu.AddSlider("LES Cs", props.Smagorinsky)
+	spinSlider := props.Menu.AddSlider("Spin", props.Spin)
+	forceSlider := props.Menu.AddSlider("Force", getForceString(props.Force))
+	edgeSlider := props.Menu.AddSlider("Edge", getEdgeString(props.Edge))
+	boundarySlider := props.Menu.AddSlider("BC", getBoundaryString(props.Boundaries[props.Edge]))
 	renderSlider := props.Menu.AddSlider("Rndr", getRenderTypeString(props.RenderOpt))
+	touchSlider := props.Menu.AddSlider("Touch", getTouchModeString(props.TouchMode))
+	tracerSlider := props.Menu.AddSlider("Tracer", getTracerModeString(props.Tracers))
+	stokesSlider := props.Menu.AddSlider("Stokes", props.Stokes)
+	raSlider := props.Menu.AddSlider("Ra", getRayleighString(props.Rayleigh))
+	prSlider := props.Menu.AddSlider("Pr", getPrandtlString(props.Prandtl))
+	gSlider := props.Menu.AddSlider("G", getInteractionString(props.Interaction))
+	angleSlider := props.Menu.AddSlider("Angle", getContactAngleString(props.Wetting))
+	ratioSlider := props.Menu.AddSlider("V-Rat", getViscosityRatioString(props.ViscosityRatio))
+	tensionSlider := props.Menu.AddSlider("Sigma", getTensionString(props.Tension))
+	porousSlider := props.Menu.AddSlider("Solid", getSolidFractionString(props.Porosity))
+	dyeSlider := props.Menu.AddSlider("Dye", getDyeString(props.Dye))
+	linesSlider := props.Menu.AddSlider("Lines", getStreamlineString(props.Streamlines))
+	lineColorSlider := props.Menu.AddSlider("L-Col", getStreamlineColorString(props.StreamlineColor))
 	pauseSimulation := props.Menu.AddButton("Pause")
+	exportButton := props.Menu.AddButton("Export")
 	applyButton := props.Menu.AddButton("Apply")
 	cancelButton := props.Menu.AddButton("Cancel")
 
@@ -87,17 +357,20 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 	min.E[uiengine.X] = -1
 	min.E[uiengine.Y] = -1
 	max.E[uiengine.X] = 1
-	max.E[uiengine.Y] = -0.88
+	max.E[uiengine.Y] = bottomBarTop
 	props.BottomBar = ui.AddHorizontalWindow(min, max, image_color.RGBA{230, 230, 230, 255})
 	props.BottomBar.SetPadding(0.00)
 	props.Fps = props.BottomBar.AddLabel("FPS")
 	menuButton := props.BottomBar.AddButton("Menu")
 	bottomBarDisp := props.BottomBar.AddLabel(getPlotTypeString(props.Plot))
+	props.Reynolds = props.BottomBar.AddLabel("Re")
+	props.Forces = props.BottomBar.AddLabel("Cd")
+	props.Strouhal = props.BottomBar.AddLabel("St")
 
 	props.BottomBar.Build(ui)
 
 	min.E[uiengine.X] = -1
-	min.E[uiengine.Y] = -0.88
+	min.E[uiengine.Y] = bottomBarTop
 	max.E[uiengine.X] = 1
 	max.E[uiengine.Y] = 0
 	props.DebugWindow = ui.AddWindow(min, max, image_color.RGBA{245, 245, 245, 255})
@@ -172,15 +445,15 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 
 	// VISCOCITY
 	visSlider.RegisterHandlerLeft(func(pro *AppProperties) float32 {
-		pro.Fvis -= 0.005
-		if pro.Fvis <= 0.005 {
-			pro.Fvis = 0.005
+		pro.Fvis -= getViscStep(pro.Fvis, false)
+		if pro.Fvis <= 0.001 {
+			pro.Fvis = 0.001
 		}
 		return pro.Fvis
 	}, p)
 
 	visSlider.RegisterHandlerRight(func(pro *AppProperties) float32 {
-		pro.Fvis += 0.005
+		pro.Fvis += getViscStep(pro.Fvis, true)
 		if pro.Fvis >= 0.2 {
 			pro.Fvis = 0.2
 		}
@@ -190,7 +463,7 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 	// PLOT DISPLAY
 	plotSlider.RegisterHandlerRight(func(pro *AppProperties) string {
 		pro.Plot++
-		if pro.Plot > 4 {
+		if pro.Plot >= lbm.NumPlotTypes {
 			pro.Plot = 0
 		}
 		bottomBarDisp.SetText(pro.UI, getPlotTypeString(pro.Plot))
@@ -200,7 +473,7 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 	plotSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
 		pro.Plot--
 		if pro.Plot < 0 {
-			pro.Plot = 4
+			pro.Plot = lbm.NumPlotTypes - 1
 		}
 		bottomBarDisp.SetText(pro.UI, getPlotTypeString(pro.Plot))
 		return getPlotTypeString(pro.Plot)
@@ -208,21 +481,167 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 
 	// BARRIER DISPLAY
 	barrierSlider.RegisterHandlerRight(func(pro *AppProperties) string {
-		pro.Barrier++
-		if pro.Barrier > 1 {
-			pro.Barrier = 0
+		btype := pro.Barrier + 1
+		if btype > lbm.POROUS {
+			btype = 0
 		}
+		setBarrierType(pro, btype)
+		boundarySlider.SetValueText(pro.UI, getBoundaryString(pro.Boundaries[pro.Edge]))
+		visSlider.SetValueText(pro.UI, fmt.Sprintf("%.3f", pro.Fvis))
 		return getBarrierString(pro.Barrier)
 	}, p)
 
 	barrierSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
-		pro.Barrier--
-		if pro.Barrier < 0 {
-			pro.Barrier = 1
+		btype := pro.Barrier - 1
+		if btype < 0 {
+			btype = lbm.POROUS
 		}
+		setBarrierType(pro, btype)
+		boundarySlider.SetValueText(pro.UI, getBoundaryString(pro.Boundaries[pro.Edge]))
+		visSlider.SetValueText(pro.UI, fmt.Sprintf("%.3f", pro.Fvis))
 		return getBarrierString(pro.Barrier)
 	}, p)
 
+	// COLLISION OPERATOR
+	collisionSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Collision++
+		if pro.Collision >= len(lbm.CollisionOperatorNames()) {
+			pro.Collision = 0
+		}
+		return getCollisionString(pro.Collision)
+	}, p)
+
+	collisionSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Collision--
+		if pro.Collision < 0 {
+			pro.Collision = len(lbm.CollisionOperatorNames()) - 1
+		}
+		return getCollisionString(pro.Collision)
+	}, p)
+
+	// TRT MAGIC PARAMETER
+	magicSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Magic--
+		if pro.Magic < 0 {
+			pro.Magic = 0
+		}
+		return getMagicString(pro.Magic)
+	}, p)
+
+	magicSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Magic++
+		if pro.Magic >= len(magicParameters) {
+			pro.Magic = len(magicParameters) - 1
+		}
+		return getMagicString(pro.Magic)
+	}, p)
+
+	// MRT GHOST MOMENT RELAXATION RATE
+	ghostSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Ghost--
+		if pro.Ghost < 0 {
+			pro.Ghost = 0
+		}
+		return getGhostRateString(pro.Ghost)
+	}, p)
+
+	ghostSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Ghost++
+		if pro.Ghost >= len(ghostRates) {
+			pro.Ghost = len(ghostRates) - 1
+		}
+		return getGhostRateString(pro.Ghost)
+	}, p)
+
+	// SMAGORINSKY CONSTANT
+	smagorinskySlider.RegisterHandlerLeft(func(pro *AppProperties) float32 {
+		pro.Smagorinsky -= 0.02
+		if pro.Smagorinsky <= 0 {
+			pro.Smagorinsky = 0
+		}
+		return pro.Smagorinsky
+	}, p)
+
+	smagorinskySlider.RegisterHandlerRight(func(pro *AppProperties) float32 {
+		pro.Smagorinsky += 0.02
+		if pro.Smagorinsky >= 0.3 {
+			pro.Smagorinsky = 0.3
+		}
+		return pro.Smagorinsky
+	}, p)
+
+	// BARRIER SPIN
+	spinSlider.RegisterHandlerLeft(func(pro *AppProperties) float32 {
+		pro.Spin -= 0.5
+		if pro.Spin <= -4 {
+			pro.Spin = -4
+		}
+		return pro.Spin
+	}, p)
+
+	spinSlider.RegisterHandlerRight(func(pro *AppProperties) float32 {
+		pro.Spin += 0.5
+		if pro.Spin >= 4 {
+			pro.Spin = 4
+		}
+		return pro.Spin
+	}, p)
+
+	// EXTERNAL FORCE
+	forceSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Force--
+		if pro.Force < 0 {
+			pro.Force = 0
+		}
+		return getForceString(pro.Force)
+	}, p)
+
+	forceSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Force++
+		if pro.Force >= len(externalForces) {
+			pro.Force = len(externalForces) - 1
+		}
+		return getForceString(pro.Force)
+	}, p)
+
+	// EDGE SELECTION
+	edgeSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Edge++
+		if pro.Edge >= lbm.NumEdges {
+			pro.Edge = 0
+		}
+		boundarySlider.SetValueText(pro.UI, getBoundaryString(pro.Boundaries[pro.Edge]))
+		return getEdgeString(pro.Edge)
+	}, p)
+
+	edgeSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Edge--
+		if pro.Edge < 0 {
+			pro.Edge = lbm.NumEdges - 1
+		}
+		boundarySlider.SetValueText(pro.UI, getBoundaryString(pro.Boundaries[pro.Edge]))
+		return getEdgeString(pro.Edge)
+	}, p)
+
+	// BOUNDARY CONDITION OF THE SELECTED EDGE
+	boundarySlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		btype := pro.Boundaries[pro.Edge] + 1
+		if btype >= lbm.NumBoundaryTypes {
+			btype = 0
+		}
+		setEdgeBoundary(pro, pro.Edge, btype)
+		return getBoundaryString(btype)
+	}, p)
+
+	boundarySlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		btype := pro.Boundaries[pro.Edge] - 1
+		if btype < 0 {
+			btype = lbm.NumBoundaryTypes - 1
+		}
+		setEdgeBoundary(pro, pro.Edge, btype)
+		return getBoundaryString(btype)
+	}, p)
+
 	// Render Type
 	renderSlider.RegisterHandlerRight(func(pro *AppProperties) string {
 		pro.RenderOpt++
@@ -251,11 +670,263 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 		return true
 	}, &buttonHandlerData{p, pauseSimulation})
 
+	// TOUCH MODE
+	touchSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.TouchMode--
+		if pro.TouchMode < 0 {
+			pro.TouchMode = NumTouchModes - 1
+		}
+		return getTouchModeString(pro.TouchMode)
+	}, p)
+
+	touchSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.TouchMode++
+		if pro.TouchMode >= NumTouchModes {
+			pro.TouchMode = 0
+		}
+		return getTouchModeString(pro.TouchMode)
+	}, p)
+
+	// TRACERS
+	tracerSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Tracers--
+		if pro.Tracers < 0 {
+			pro.Tracers = lbm.NumTracerModes - 1
+		}
+		solver.SeedTracers(pro.Tracers, tracerSpacing)
+		return getTracerModeString(pro.Tracers)
+	}, p)
+
+	tracerSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Tracers++
+		if pro.Tracers >= lbm.NumTracerModes {
+			pro.Tracers = 0
+		}
+		solver.SeedTracers(pro.Tracers, tracerSpacing)
+		return getTracerModeString(pro.Tracers)
+	}, p)
+
+	// TRACER STOKES NUMBER
+	stokesSlider.RegisterHandlerLeft(func(pro *AppProperties) float32 {
+		pro.Stokes -= 0.25
+		if pro.Stokes <= 0 {
+			pro.Stokes = 0
+		}
+		solver.SetTracerStokes(pro.Stokes)
+		return pro.Stokes
+	}, p)
+
+	stokesSlider.RegisterHandlerRight(func(pro *AppProperties) float32 {
+		pro.Stokes += 0.25
+		if pro.Stokes >= 2 {
+			pro.Stokes = 2
+		}
+		solver.SetTracerStokes(pro.Stokes)
+		return pro.Stokes
+	}, p)
+
+	// RAYLEIGH NUMBER
+	raSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Rayleigh--
+		if pro.Rayleigh < 0 {
+			pro.Rayleigh = 0
+		}
+		solver.SetRayleighNumber(rayleighNumbers[pro.Rayleigh])
+		return getRayleighString(pro.Rayleigh)
+	}, p)
+
+	raSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Rayleigh++
+		if pro.Rayleigh >= len(rayleighNumbers) {
+			pro.Rayleigh = len(rayleighNumbers) - 1
+		}
+		solver.SetRayleighNumber(rayleighNumbers[pro.Rayleigh])
+		return getRayleighString(pro.Rayleigh)
+	}, p)
+
+	// PRANDTL NUMBER
+	prSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Prandtl--
+		if pro.Prandtl < 0 {
+			pro.Prandtl = 0
+		}
+		solver.SetPrandtlNumber(prandtlNumbers[pro.Prandtl])
+		return getPrandtlString(pro.Prandtl)
+	}, p)
+
+	prSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Prandtl++
+		if pro.Prandtl >= len(prandtlNumbers) {
+			pro.Prandtl = len(prandtlNumbers) - 1
+		}
+		solver.SetPrandtlNumber(prandtlNumbers[pro.Prandtl])
+		return getPrandtlString(pro.Prandtl)
+	}, p)
+
+	// SHAN-CHEN INTERACTION STRENGTH
+	gSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Interaction--
+		if pro.Interaction < 0 {
+			pro.Interaction = 0
+		}
+		solver.SetShanChenStrength(interactionStrengths[pro.Interaction])
+		return getInteractionString(pro.Interaction)
+	}, p)
+
+	gSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Interaction++
+		if pro.Interaction >= len(interactionStrengths) {
+			pro.Interaction = len(interactionStrengths) - 1
+		}
+		solver.SetShanChenStrength(interactionStrengths[pro.Interaction])
+		return getInteractionString(pro.Interaction)
+	}, p)
+
+	// CONTACT ANGLE
+	angleSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Wetting--
+		if pro.Wetting < 0 {
+			pro.Wetting = 0
+		}
+		solver.SetContactAngle(contactAngles[pro.Wetting])
+		return getContactAngleString(pro.Wetting)
+	}, p)
+
+	angleSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Wetting++
+		if pro.Wetting >= len(contactAngles) {
+			pro.Wetting = len(contactAngles) - 1
+		}
+		solver.SetContactAngle(contactAngles[pro.Wetting])
+		return getContactAngleString(pro.Wetting)
+	}, p)
+
+	// VISCOSITY RATIO OF THE IMMISCIBLE FLUIDS
+	ratioSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.ViscosityRatio--
+		if pro.ViscosityRatio < 0 {
+			pro.ViscosityRatio = 0
+		}
+		solver.SetSecondViscosity(pro.Fvis * viscosityRatios[pro.ViscosityRatio])
+		return getViscosityRatioString(pro.ViscosityRatio)
+	}, p)
+
+	ratioSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.ViscosityRatio++
+		if pro.ViscosityRatio >= len(viscosityRatios) {
+			pro.ViscosityRatio = len(viscosityRatios) - 1
+		}
+		solver.SetSecondViscosity(pro.Fvis * viscosityRatios[pro.ViscosityRatio])
+		return getViscosityRatioString(pro.ViscosityRatio)
+	}, p)
+
+	// SURFACE TENSION
+	tensionSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Tension--
+		if pro.Tension < 0 {
+			pro.Tension = 0
+		}
+		solver.SetSurfaceTension(surfaceTensions[pro.Tension])
+		return getTensionString(pro.Tension)
+	}, p)
+
+	tensionSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Tension++
+		if pro.Tension >= len(surfaceTensions) {
+			pro.Tension = len(surfaceTensions) - 1
+		}
+		solver.SetSurfaceTension(surfaceTensions[pro.Tension])
+		return getTensionString(pro.Tension)
+	}, p)
+
+	// SOLID FRACTION OF POROUS SITES
+	porousSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Porosity--
+		if pro.Porosity < 0 {
+			pro.Porosity = 0
+		}
+		solver.SetPorousFraction(solidFractions[pro.Porosity])
+		return getSolidFractionString(pro.Porosity)
+	}, p)
+
+	porousSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Porosity++
+		if pro.Porosity >= len(solidFractions) {
+			pro.Porosity = len(solidFractions) - 1
+		}
+		solver.SetPorousFraction(solidFractions[pro.Porosity])
+		return getSolidFractionString(pro.Porosity)
+	}, p)
+
+	// DYE
+	dyeSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Dye--
+		if pro.Dye < 0 {
+			pro.Dye = numDyeModes - 1
+		}
+		setDye(pro)
+		return getDyeString(pro.Dye)
+	}, p)
+
+	dyeSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Dye++
+		if pro.Dye >= numDyeModes {
+			pro.Dye = 0
+		}
+		setDye(pro)
+		return getDyeString(pro.Dye)
+	}, p)
+
+	// STREAMLINES
+	linesSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.Streamlines--
+		if pro.Streamlines < 0 {
+			pro.Streamlines = len(streamlineSpacings) - 1
+		}
+		setStreamlines(pro)
+		return getStreamlineString(pro.Streamlines)
+	}, p)
+
+	linesSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.Streamlines++
+		if pro.Streamlines >= len(streamlineSpacings) {
+			pro.Streamlines = 0
+		}
+		setStreamlines(pro)
+		return getStreamlineString(pro.Streamlines)
+	}, p)
+
+	lineColorSlider.RegisterHandlerLeft(func(pro *AppProperties) string {
+		pro.StreamlineColor--
+		if pro.StreamlineColor < 0 {
+			pro.StreamlineColor = len(streamlineColors) - 1
+		}
+		setStreamlines(pro)
+		return getStreamlineColorString(pro.StreamlineColor)
+	}, p)
+
+	lineColorSlider.RegisterHandlerRight(func(pro *AppProperties) string {
+		pro.StreamlineColor++
+		if pro.StreamlineColor >= len(streamlineColors) {
+			pro.StreamlineColor = 0
+		}
+		setStreamlines(pro)
+		return getStreamlineColorString(pro.StreamlineColor)
+	}, p)
+
+	// Export probe time series
+	exportButton.RegisterHandler(func(pro *AppProperties) bool {
+		pro.ExportProbes()
+		return true
+	}, p)
+
 	// Apply
 	applyButton.RegisterHandler(func(pro *AppProperties) bool {
 		// Xdim, Ydim - Used only in the UI
 		// XGrid, YGrid - Used for the actual simulation grid
 
+		pro.ApplyPhysics()
+
 		// If grid has changed, update grid
 		if (pro.Xdim != pro.XGrid) || (pro.Ydim != pro.YGrid) {
 
@@ -288,6 +959,14 @@ func BuildUI(ui *uiengine.UiEngine, gridx, gridy int) {
 			solver.InitalizeLattice(pro.XGrid, pro.YGrid, pro.Fvel, pro.Fvis, pro.Barrier)
 		}
 
+		// The lid-driven cavity, natural convection, multiphase and fingering
+		// barriers set their own boundaries
+		for edge := range pro.Boundaries {
+			pro.Boundaries[edge] = solver.Boundary(edge)
+		}
+		boundarySlider.SetValueText(pro.UI, getBoundaryString(pro.Boundaries[pro.Edge]))
+		solver.SetCollisionOperator(pro.CollisionOperator())
+
 		pro.ToggleMenu()
 		return true
 	}, p)